@@ -2,7 +2,6 @@ package bootstrap
 
 import (
 	"context"
-	"fmt"
 	"github.com/plgd-dev/go-coap/v3/message"
 	"github.com/plgd-dev/go-coap/v3/message/codes"
 	"github.com/plgd-dev/go-coap/v3/mux"
@@ -32,7 +31,7 @@ func (c *Client) finish(ctx context.Context) (err error) {
 		return err
 	}
 	if res.Code() != codes.Changed {
-		return fmt.Errorf("unexpected response code: %v", res.Code())
+		return core.NewResponseError("bootstrap finish", "/bs", res)
 	}
 	return nil
 }
@@ -51,7 +50,7 @@ func (c *Client) Write(ctx context.Context, path node.Path, val ...node.Node) er
 		return err
 	}
 	if resp.Code() != codes.Changed {
-		return fmt.Errorf("unexpected response code: %v", resp.Code())
+		return core.NewResponseError("write", path.String(), resp)
 	}
 	return nil
 }
@@ -59,11 +58,11 @@ func (c *Client) Write(ctx context.Context, path node.Path, val ...node.Node) er
 func (c *Client) Read(ctx context.Context, path node.Path) ([]node.Node, error) {
 
 	// accept
-	buf := make([]byte, 2)
-	_, _ = message.EncodeUint32(buf, uint32(c.selectedCt))
+	buf := make([]byte, 4)
+	l, _ := message.EncodeUint32(buf, uint32(c.selectedCt))
 	acceptOption := message.Option{
 		ID:    message.Accept,
-		Value: buf[:],
+		Value: buf[:l],
 	}
 
 	msg, err := c.conn.Get(ctx, path.String(), acceptOption)
@@ -71,7 +70,7 @@ func (c *Client) Read(ctx context.Context, path node.Path) ([]node.Node, error)
 		return nil, err
 	}
 	if msg.Code() != codes.Content {
-		return nil, fmt.Errorf("unexpected response code: %v", msg.Code())
+		return nil, core.NewResponseError("read", path.String(), msg)
 	}
 	if msg.Body() == nil {
 		return nil, core.ErrEmptyBody
@@ -92,24 +91,34 @@ func (c *Client) Discover(ctx context.Context, path node.Path) ([]*encoding.Core
 		return nil, err
 	}
 	if r.Code() != codes.Content {
-		return nil, fmt.Errorf("unexpected response code: %v", r.Code())
+		return nil, core.NewResponseError("discover", path.String(), r)
 	}
 	links := make([]*encoding.CoreLink, 0)
 	if r.Body() != nil {
 		if b, err := io.ReadAll(r.Body()); err == nil {
-			links, _ = encoding.CoreLinksFromString(string(b))
+			return encoding.CoreLinksFromString(string(b))
 		}
 	}
 	return links, nil
 }
 
+// DiscoverTree discover path and return the result as a tree of entries
+// with parsed attributes, Version is set from the root link
+func (c *Client) DiscoverTree(ctx context.Context, path node.Path) (*node.Discovery, error) {
+	links, err := c.Discover(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return node.NewDiscovery(links)
+}
+
 func (c *Client) Delete(ctx context.Context, path node.Path) (err error) {
 	resp, err := c.conn.Delete(ctx, path.String())
 	if err != nil {
 		return err
 	}
 	if resp.Code() != codes.Deleted {
-		return fmt.Errorf("unexpected response code: %v", resp.Code())
+		return core.NewResponseError("delete", path.String(), resp)
 	}
 	return nil
 }
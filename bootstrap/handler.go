@@ -64,6 +64,11 @@ func (h *Handler) ServeCOAP(w mux.ResponseWriter, r *mux.Message) {
 	case message.AppLwm2mTLV:
 	case message.TextPlain:
 	case message.AppOctets:
+	case message.AppCBOR:
+	case message.AppSenmlJSON:
+	case message.AppSenmlCbor:
+	case message.AppLwm2mCbor:
+	case message.AppLwm2mJSON:
 	default:
 		h.logger.Debugf("client prefer unsupported(yet) content-type %s", pct.String())
 		h.logger.Debugf("using %s as default", DefaultContentType.String())
@@ -0,0 +1,240 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"github.com/plgd-dev/go-coap/v3/message"
+	"github.com/plgd-dev/go-coap/v3/message/codes"
+	"github.com/plgd-dev/go-coap/v3/message/pool"
+	"github.com/plgd-dev/go-coap/v3/mux"
+	"github.com/yplam/lwm2m/node"
+)
+
+// CoAP request codes of RFC8132 that go-coap does not define
+const (
+	codeFETCH  codes.Code = 5
+	codeIPATCH codes.Code = 7
+)
+
+// compositeMediaType return t if it can be used by composite operations,
+// SenML JSON otherwise
+func compositeMediaType(t message.MediaType) message.MediaType {
+	switch t {
+	case message.AppSenmlJSON, message.AppSenmlCbor:
+		return t
+	}
+	return message.AppSenmlJSON
+}
+
+// newCompositeRequest create a root path request with code and the SenML
+// path list of paths as payload
+func (d *Device) newCompositeRequest(ctx context.Context, code codes.Code, paths []node.Path) (*pool.Message, error) {
+	reqType := compositeMediaType(d.writeMediaType)
+	payload, err := node.EncodePathList(reqType, paths)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 4)
+	l, _ := message.EncodeUint32(buf, uint32(compositeMediaType(d.acceptMediaType)))
+	root := node.NewRootPath()
+	req, err := d.conn.NewGetRequest(ctx, root.String(), message.Option{
+		ID:    message.Accept,
+		Value: buf[:l],
+	})
+	if err != nil {
+		return nil, err
+	}
+	req.SetCode(code)
+	req.SetContentFormat(reqType)
+	req.SetBody(payload)
+	return req, nil
+}
+
+// ReadComposite read resources of several objects with one FETCH request,
+// paths may be object, object instance, resource or resource instance
+// paths. Returned resources are keyed by their path.
+//
+// Composite operations use SenML CBOR if it is the selected media type,
+// SenML JSON otherwise
+func (d *Device) ReadComposite(ctx context.Context, paths ...node.Path) (map[node.Path]*node.Resource, error) {
+	req, err := d.newCompositeRequest(ctx, codeFETCH, paths)
+	if err != nil {
+		return nil, err
+	}
+	defer d.conn.ReleaseMessage(req)
+	resp, err := d.conn.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer d.conn.ReleaseMessage(resp)
+	root := node.NewRootPath()
+	if resp.Code() != codes.Content {
+		return nil, NewResponseError("read composite", root.String(), resp)
+	}
+	if resp.Body() == nil {
+		return nil, ErrEmptyBody
+	}
+	nodes, err := node.DecodeMessage(root, resp)
+	if err != nil {
+		return nil, err
+	}
+	d.UpdateState(root, nodes, StateRead)
+	return node.GetAllResources(nodes, root)
+}
+
+// WriteComposite write resources of several objects with one iPATCH
+// request, keys are resource or resource instance paths and values are Go
+// values checked against the registry resource types, see
+// node.NewResourceInstanceValue.
+//
+// Values are all checked before sending, and the device apply the request
+// atomically, so an error means none of the values was written
+func (d *Device) WriteComposite(ctx context.Context, values map[node.Path]any) error {
+	if len(values) == 0 {
+		return node.ErrEmpty
+	}
+	paths := make([]node.Path, 0, len(values))
+	for p := range values {
+		paths = append(paths, p)
+	}
+	node.SortPaths(paths)
+	nodes := make([]node.Node, 0, len(paths))
+	for _, p := range paths {
+		var n node.Node
+		var err error
+		switch {
+		case p.IsResource():
+			n, err = node.NewSingleResourceValue(p, values[p])
+		case p.IsResourceInstance():
+			n, err = node.NewResourceInstanceValue(p, values[p])
+		default:
+			err = node.ErrPathInvalidValue
+		}
+		if err != nil {
+			return fmt.Errorf("write composite %v: %w", p.String(), err)
+		}
+		nodes = append(nodes, n)
+	}
+	reqType := compositeMediaType(d.writeMediaType)
+	payload, err := node.EncodeMessage(reqType, nodes)
+	if err != nil {
+		return err
+	}
+	root := node.NewRootPath()
+	req, err := d.conn.NewPutRequest(ctx, root.String(), reqType, payload)
+	if err != nil {
+		return err
+	}
+	defer d.conn.ReleaseMessage(req)
+	req.SetCode(codeIPATCH)
+	resp, err := d.conn.Do(req)
+	if err != nil {
+		return err
+	}
+	defer d.conn.ReleaseMessage(resp)
+	if resp.Code() != codes.Changed {
+		return NewResponseError("write composite", root.String(), resp)
+	}
+	return nil
+}
+
+// ObserveComposite observe several paths with one Observe FETCH request,
+// each notification is delivered to onMsg as a single update holding the
+// resources of all the observed paths that are reported.
+//
+// Like Observe, the observation is established by the device run loop and
+// re-established when canceled
+func (d *Device) ObserveComposite(paths []node.Path, onMsg ObserveCompositeFunc) error {
+	return d.ObserveCompositeWithMeta(paths, func(d *Device, notify map[node.Path]*node.Resource, meta *Notification) {
+		onMsg(d, notify)
+	})
+}
+
+// ObserveCompositeWithMeta is ObserveComposite with a callback that receive
+// the metadata of each notification
+func (d *Device) ObserveCompositeWithMeta(paths []node.Path, onMsg ObserveCompositeFuncWithMeta) error {
+	if len(paths) == 0 {
+		return node.ErrEmpty
+	}
+	key, sorted := newCompositeKey(paths)
+	_ = d.cancelObservation(key)
+	d.observations.Store(key, Observation{
+		o:     nil,
+		cb:    wrapObserveCompositeFunc(onMsg),
+		paths: sorted,
+	})
+	return nil
+}
+
+// CancelObserveComposite cancel the composite observation of paths, paths
+// may be in any order
+func (d *Device) CancelObserveComposite(paths ...node.Path) error {
+	key, _ := newCompositeKey(paths)
+	return d.cancelObservation(key)
+}
+
+func (d *Device) processCompositeObservation(ctx context.Context, key any, paths []node.Path) (mux.Observation, error) {
+	req, err := d.newCompositeRequest(ctx, codeFETCH, paths)
+	if err != nil {
+		return nil, err
+	}
+	defer d.conn.ReleaseMessage(req)
+	req.SetObserve(0)
+	root := node.NewRootPath()
+	return d.conn.DoObserve(req, func(notification *pool.Message) {
+		if notification.Body() == nil {
+			return
+		}
+		d.MarkAwake()
+		nodes, err := node.DecodeMessage(root, notification)
+		if err != nil {
+			d.count(func(s *NotificationStats) { s.DroppedInvalid++ })
+			return
+		}
+		meta := d.newNotification(notification)
+		meta.Path = root
+		meta.Paths = paths
+		d.dispatch(observationEvent{
+			key:  key,
+			p:    root,
+			n:    nodes,
+			meta: meta,
+		})
+	})
+}
+
+// releaseObservation remove o from the observations of the connection
+// without a request, Cancel fail to send its GET with a done ctx
+func releaseObservation(o mux.Observation) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_ = o.Cancel(ctx)
+}
+
+// cancelCompositeObservation send the Cancel Observation-Composite FETCH
+// request of o, it use the token of the observation so that the device can
+// match it
+func (d *Device) cancelCompositeObservation(ctx context.Context, o Observation) error {
+	r, ok := o.o.(interface{ Request() message.Message })
+	if !ok {
+		return ErrNotFound
+	}
+	releaseObservation(o.o)
+	req, err := d.newCompositeRequest(ctx, codeFETCH, o.paths)
+	if err != nil {
+		return err
+	}
+	defer d.conn.ReleaseMessage(req)
+	req.SetObserve(1)
+	req.SetToken(r.Request().Token)
+	resp, err := d.conn.Do(req)
+	if err != nil {
+		return err
+	}
+	defer d.conn.ReleaseMessage(resp)
+	if resp.Code() != codes.Content {
+		root := node.NewRootPath()
+		return NewResponseError("cancel observe composite", root.String(), resp)
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package core
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/plgd-dev/go-coap/v3/message"
+	"github.com/plgd-dev/go-coap/v3/message/codes"
+	"github.com/plgd-dev/go-coap/v3/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/yplam/lwm2m/node"
+)
+
+func TestReadComposite(t *testing.T) {
+	reqs := make(chan testRequest, 1)
+	d := newTestDevice(t, func(w mux.ResponseWriter, r *mux.Message) {
+		reqs <- newTestRequest(r)
+		_ = w.SetResponse(codes.Content, message.AppSenmlJSON, bytes.NewReader([]byte(
+			`[{"bn":"/3/0/","n":"9","v":95},{"bn":"/4/0/","n":"2","v":-70},{"bn":"/3303/0/","n":"5700","v":21.5}]`)))
+	})
+
+	ress, err := d.ReadComposite(testContext(t), node.NewResourcePath(3, 0, 9),
+		node.NewResourcePath(4, 0, 2), node.NewResourcePath(3303, 0, 5700))
+	assert.Nil(t, err)
+	req := <-reqs
+	assert.Equal(t, codeFETCH, req.code)
+	assert.Equal(t, message.AppSenmlJSON, req.format)
+	assert.Equal(t, `[{"n":"/3/0/9"},{"n":"/4/0/2"},{"n":"/3303/0/5700"}]`, string(req.body))
+	assert.Equal(t, 3, len(ress))
+	res, ok := ress[node.NewResourcePath(3, 0, 9)]
+	assert.True(t, ok)
+	ins, err := res.GetInstance(0)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(95), ins.Value())
+	res, ok = ress[node.NewResourcePath(3303, 0, 5700)]
+	assert.True(t, ok)
+	ins, err = res.GetInstance(0)
+	assert.Nil(t, err)
+	assert.Equal(t, 21.5, ins.Value())
+}
+
+func TestReadCompositeError(t *testing.T) {
+	d := newTestDevice(t, func(w mux.ResponseWriter, r *mux.Message) {
+		_ = w.SetResponse(codes.NotFound, message.TextPlain, nil)
+	})
+	_, err := d.ReadComposite(testContext(t), node.NewResourcePath(3, 0, 9))
+	assert.ErrorIs(t, err, ErrUnexpectedResponseCode)
+	_, err = d.ReadComposite(testContext(t))
+	assert.Equal(t, node.ErrEmpty, err)
+}
+
+func TestWriteComposite(t *testing.T) {
+	reqs := make(chan testRequest, 1)
+	d := newTestDevice(t, func(w mux.ResponseWriter, r *mux.Message) {
+		reqs <- newTestRequest(r)
+		_ = w.SetResponse(codes.Changed, message.TextPlain, nil)
+	})
+	err := d.WriteComposite(testContext(t), map[node.Path]any{
+		node.NewResourcePath(3303, 0, 5750):      "Room",
+		node.NewResourcePath(1, 0, 1):            300,
+		node.NewResourceInstancePath(3, 0, 7, 1): 5000,
+	})
+	assert.Nil(t, err)
+	req := <-reqs
+	assert.Equal(t, codeIPATCH, req.code)
+	assert.Equal(t, message.AppSenmlJSON, req.format)
+	assert.Equal(t, `[{"n":"/1/0/1","v":300},{"n":"/3/0/7/1","v":5000},{"n":"/3303/0/5750","vs":"Room"}]`,
+		string(req.body))
+
+	// values are checked before sending
+	err = d.WriteComposite(testContext(t), map[node.Path]any{
+		node.NewResourcePath(1, 0, 1): "300",
+	})
+	assert.ErrorIs(t, err, node.ErrResourceType)
+	err = d.WriteComposite(testContext(t), map[node.Path]any{
+		node.NewObjectInstancePath(1, 0): 300,
+	})
+	assert.ErrorIs(t, err, node.ErrPathInvalidValue)
+	assert.Equal(t, 0, len(reqs))
+
+	// resources of vendor objects can be written
+	err = d.WriteComposite(testContext(t), map[node.Path]any{
+		node.NewResourcePath(32769, 0, 1): 7,
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, `[{"n":"/32769/0/1","v":7}]`, string((<-reqs).body))
+}
+
+func TestWriteCompositeError(t *testing.T) {
+	d := newTestDevice(t, func(w mux.ResponseWriter, r *mux.Message) {
+		_ = w.SetResponse(codes.BadRequest, message.TextPlain, nil)
+	})
+	d.SetMediaTypes(message.AppSenmlCbor, message.AppSenmlCbor)
+	err := d.WriteComposite(testContext(t), map[node.Path]any{
+		node.NewResourcePath(1, 0, 1): 300,
+	})
+	assert.ErrorIs(t, err, ErrUnexpectedResponseCode)
+}
+
+func TestObserveComposite(t *testing.T) {
+	reqs := make(chan testRequest, 2)
+	d := newTestDevice(t, func(w mux.ResponseWriter, r *mux.Message) {
+		req := newTestRequest(r)
+		reqs <- req
+		if req.observe == 0 {
+			_ = w.SetResponse(codes.Content, message.AppSenmlJSON, bytes.NewReader([]byte(
+				`[{"bn":"/3/0/","n":"9","v":95},{"bn":"/3303/0/","n":"5700","v":21.5}]`)))
+			w.Message().SetObserve(2)
+			return
+		}
+		_ = w.SetResponse(codes.Content, message.TextPlain, nil)
+	})
+	go d.run()
+
+	notifies := make(chan map[node.Path]*node.Resource, 1)
+	err := d.ObserveComposite([]node.Path{node.NewResourcePath(3303, 0, 5700), node.NewResourcePath(3, 0, 9)},
+		func(d *Device, notify map[node.Path]*node.Resource) {
+			notifies <- notify
+		})
+	assert.Nil(t, err)
+
+	var notify map[node.Path]*node.Resource
+	select {
+	case notify = <-notifies:
+	case <-testContext(t).Done():
+		t.Fatal("no notification")
+	}
+	assert.Equal(t, 2, len(notify))
+	res, ok := notify[node.NewResourcePath(3303, 0, 5700)]
+	assert.True(t, ok)
+	ins, err := res.GetInstance(0)
+	assert.Nil(t, err)
+	assert.Equal(t, 21.5, ins.Value())
+
+	req := <-reqs
+	assert.Equal(t, codeFETCH, req.code)
+	assert.Equal(t, 0, req.observe)
+	assert.Equal(t, `[{"n":"/3/0/9"},{"n":"/3303/0/5700"}]`, string(req.body))
+
+	key, _ := newCompositeKey([]node.Path{node.NewResourcePath(3, 0, 9), node.NewResourcePath(3303, 0, 5700)})
+	var obs mux.Observation
+	assert.Eventually(t, func() bool {
+		if val, ok := d.observations.Load(key); ok {
+			obs = val.(Observation).o
+		}
+		return obs != nil
+	}, time.Second, time.Millisecond)
+	assert.False(t, obs.Canceled())
+
+	// paths order does not matter
+	assert.Nil(t, d.CancelObserveComposite(node.NewResourcePath(3, 0, 9), node.NewResourcePath(3303, 0, 5700)))
+	var cancelReq testRequest
+	select {
+	case cancelReq = <-reqs:
+	case <-testContext(t).Done():
+		t.Fatal("no cancel request")
+	}
+	assert.Equal(t, codeFETCH, cancelReq.code)
+	assert.Equal(t, 1, cancelReq.observe)
+	assert.Equal(t, req.token, cancelReq.token)
+	assert.Equal(t, req.body, cancelReq.body)
+	select {
+	case r := <-reqs:
+		t.Fatalf("unexpected request after cancel: %v", r.code)
+	case <-time.After(100 * time.Millisecond):
+	}
+	// the observation is removed from the connection
+	assert.True(t, obs.Canceled())
+	assert.Equal(t, ErrNotFound, d.CancelObserveComposite(node.NewResourcePath(3, 0, 9)))
+	assert.Equal(t, node.ErrEmpty, d.ObserveComposite(nil, nil))
+}
+
+func TestObserveCompositeInvalid(t *testing.T) {
+	d := newTestDevice(t, func(w mux.ResponseWriter, r *mux.Message) {
+		_ = w.SetResponse(codes.Content, message.AppSenmlJSON, bytes.NewReader([]byte(
+			`[{"bn":"/3/0/","n":"9","vs":"high"}]`)))
+		if newTestRequest(r).observe == 0 {
+			w.Message().SetObserve(2)
+		}
+	})
+	go d.run()
+	err := d.ObserveComposite([]node.Path{node.NewResourcePath(3, 0, 9)},
+		func(d *Device, notify map[node.Path]*node.Resource) {
+			t.Error("invalid notification delivered")
+		})
+	assert.Nil(t, err)
+	assert.Eventually(t, func() bool {
+		return d.NotificationStats().DroppedInvalid == 1
+	}, 5*time.Second, time.Millisecond)
+}
@@ -10,14 +10,19 @@ import (
 var (
 	ErrUnexpectedResponseCode = errors.New("unexpected response code")
 	ErrEmptyBody              = errors.New("empty body")
+	ErrNoLocationPath         = errors.New("no location path")
 )
 
 type Observer interface {
 	ObserveSync(p node.Path, onMsg ObserveFunc) error
 	Observe(p node.Path, onMsg ObserveFunc) error
+	ObserveWithAttributes(ctx context.Context, p node.Path, attrs *node.Attributes, onMsg ObserveFunc) error
 	ObserveObject(p node.Path, onMsg ObserveObjectFunc) error
 	ObserveResource(p node.Path, onMsg ObserveResourceFunc) error
 	CancelObserve(p node.Path) error
+	ObserveComposite(paths []node.Path, onMsg ObserveCompositeFunc) error
+	CancelObserveComposite(paths ...node.Path) error
+	Subscribe(ctx context.Context, p node.Path, onMsg ObserveFunc) (*Subscription, error)
 }
 
 type DeviceManager interface {
@@ -25,7 +30,14 @@ type DeviceManager interface {
 	ReadObject(ctx context.Context, p node.Path) (*node.Object, error)
 	ReadResource(ctx context.Context, p node.Path) (*node.Resource, error)
 	Write(ctx context.Context, p node.Path, val ...node.Node) error
+	WriteWithMode(ctx context.Context, p node.Path, mode WriteMode, val ...node.Node) error
 	WriteResource(ctx context.Context, p node.Path, val *node.Resource) error
 	WriteObjectInstance(ctx context.Context, p node.Path, val *node.ObjectInstance) error
+	WriteResourceInstance(ctx context.Context, p node.Path, v any) error
+	AddResourceInstance(ctx context.Context, p node.Path, v any) error
 	Discover(ctx context.Context, p node.Path) ([]*encoding.CoreLink, error)
+	DiscoverTree(ctx context.Context, p node.Path) (*node.Discovery, error)
+	WriteAttributes(ctx context.Context, p node.Path, attrs *node.Attributes) error
+	ReadComposite(ctx context.Context, paths ...node.Path) (map[node.Path]*node.Resource, error)
+	WriteComposite(ctx context.Context, values map[node.Path]any) error
 }
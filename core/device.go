@@ -26,7 +26,10 @@ var (
 	NonIpBinding Binding = "N"
 )
 
+// NewBinding return the binding of b, the queue mode flag "Q" of LWM2M 1.0
+// bindings like "UQ" is ignored, see ParseBinding
 func NewBinding(b string) Binding {
+	b, _ = ParseBinding(b)
 	switch b {
 	case "T":
 		return TcpBinding
@@ -39,9 +42,20 @@ func NewBinding(b string) Binding {
 	}
 }
 
+// ParseBinding split the queue mode flag "Q" of a LWM2M 1.0 binding from
+// the binding, e.g. "UQ" return "U" and true
+func ParseBinding(b string) (string, bool) {
+	if strings.Contains(b, "Q") {
+		return strings.ReplaceAll(b, "Q", ""), true
+	}
+	return b, false
+}
+
 type observationEvent struct {
-	p node.Path
-	n []node.Node
+	key  any
+	p    node.Path
+	n    []node.Node
+	meta *Notification
 }
 
 type Device struct {
@@ -55,12 +69,38 @@ type Device struct {
 	Lifetime    int
 	Sms         *string
 	Manager     Manager
+	// Queue is set if the device use queue mode, requests are held until
+	// the device is awake
+	Queue bool
+
+	queueLock   sync.Mutex
+	awakeWindow time.Duration
+	awakeUntil  time.Time
+	pending     []chan struct{}
+	releasing   bool
+	// now return the current time of the awake window, time.Now if nil
+	now func() time.Time
 
 	objLock sync.RWMutex
 	objs    map[uint16]*node.Object
 
-	observations sync.Map //map[node.Path]Observation
-	obsChan      chan observationEvent
+	stateLock sync.RWMutex
+	states    map[node.Path]ResourceState
+
+	observations sync.Map //map[node.Path|compositeKey]Observation
+
+	notifyLock sync.Mutex
+	notifiers  map[any]*notifier
+	queueSize  int
+	overflow   OverflowPolicy
+	stats      NotificationStats
+
+	subs *subscriptions
+
+	// restoring hold the keys of observations kept from a previous
+	// registration, onRestore is called once they are re-issued
+	restoring []any
+	onRestore func(restored []RestoredObservation)
 
 	acceptMediaType message.MediaType
 	writeMediaType  message.MediaType
@@ -69,16 +109,19 @@ type Device struct {
 
 var DefaultMediaType = message.AppLwm2mTLV
 
+// SetMediaTypes select the content format used to read and write the device,
+// supported media types are TLV, SenML JSON, SenML CBOR, LWM2M CBOR,
+// LWM2M JSON, and plain text, opaque or CBOR for single resources
 func (d *Device) SetMediaTypes(acceptMediaType, writeMediaType message.MediaType) {
 	d.acceptMediaType = acceptMediaType
 	d.writeMediaType = writeMediaType
 
 	// generate option once
-	buf := make([]byte, 2)
-	_, _ = message.EncodeUint32(buf, uint32(acceptMediaType))
+	buf := make([]byte, 4)
+	l, _ := message.EncodeUint32(buf, uint32(acceptMediaType))
 	d.acceptOption = message.Option{
 		ID:    message.Accept,
-		Value: buf[:],
+		Value: buf[:l],
 	}
 }
 func (d *Device) GetAcceptMediaType() message.MediaType {
@@ -118,19 +161,33 @@ func (d *Device) ParseCoreLinks(links []*encoding.CoreLink) {
 
 func (d *Device) ObserveSync(p node.Path, onMsg ObserveFunc) error {
 	_ = d.CancelObserve(p)
-	no, err := d.processObservation(p)
-	if err != nil {
-		return err
+	if d.setObserveFunc(p, withMeta(onMsg), nil) {
+		return nil
 	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(d.Lifetime)*time.Second)
+	defer cancel()
+	// stored before the request so that the first notification is dispatched
 	d.observations.Store(p, Observation{
-		o:  no,
-		cb: onMsg,
+		cb: withMeta(onMsg),
 	})
+	if err := d.establishObservation(ctx, p); err != nil {
+		d.observations.Delete(p)
+		return err
+	}
 	return nil
 }
 
 func (d *Device) Observe(p node.Path, onMsg ObserveFunc) error {
+	return d.ObserveWithMeta(p, withMeta(onMsg))
+}
+
+// ObserveWithMeta is Observe with a callback that receive the metadata of
+// each notification
+func (d *Device) ObserveWithMeta(p node.Path, onMsg ObserveFuncWithMeta) error {
 	_ = d.CancelObserve(p)
+	if d.setObserveFunc(p, onMsg, nil) {
+		return nil
+	}
 	d.observations.Store(p, Observation{
 		o:  nil,
 		cb: onMsg,
@@ -138,6 +195,22 @@ func (d *Device) Observe(p node.Path, onMsg ObserveFunc) error {
 	return nil
 }
 
+// setObserveFunc set the callback of the observation of p if it is kept
+// for subscriptions, it return false if p is not observed
+func (d *Device) setObserveFunc(p node.Path, onMsg ObserveFuncWithMeta, attrs *node.Attributes) bool {
+	d.subs.lock.Lock()
+	defer d.subs.lock.Unlock()
+	val, ok := d.observations.Load(p)
+	if !ok {
+		return false
+	}
+	v := val.(Observation)
+	v.cb = onMsg
+	v.attrs = attrs
+	d.observations.Store(p, v)
+	return true
+}
+
 func (d *Device) ObserveObject(p node.Path, onMsg ObserveObjectFunc) error {
 	if !p.IsObject() {
 		return node.ErrPathInvalidValue
@@ -152,38 +225,72 @@ func (d *Device) ObserveResource(p node.Path, onMsg ObserveResourceFunc) error {
 	return d.Observe(p, wrapObserveResourceFunc(onMsg))
 }
 
+// CancelObserve cancel the observation of p, the observation is kept
+// without callback if it serve subscriptions
 func (d *Device) CancelObserve(p node.Path) error {
-	v, ok := d.observations.LoadAndDelete(p)
+	d.subs.lock.Lock()
+	defer d.subs.lock.Unlock()
+	if val, ok := d.observations.Load(p); ok && d.hasSubscribers(p) {
+		v := val.(Observation)
+		if v.cb == nil {
+			return ErrNotFound
+		}
+		v.cb = nil
+		v.attrs = nil
+		d.observations.Store(p, v)
+		return nil
+	}
+	if err := d.cancelObservation(p); err != nil {
+		return err
+	}
+	d.pruneObservations()
+	return nil
+}
+
+// cancelObservation remove the observation of key, the device is notified
+// asynchronously
+func (d *Device) cancelObservation(key any) error {
+	v, ok := d.observations.LoadAndDelete(key)
 	if !ok {
 		return ErrNotFound
 	}
+	d.stopNotifier(key)
 	o := v.(Observation)
 	if o.o != nil {
 		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(d.Lifetime)*time.Second)
 		go func() {
-			o.o.Cancel(ctx)
 			defer cancel()
+			if len(o.paths) > 0 {
+				// the FETCH deregister the composite observation, Cancel
+				// would send a GET on the root path
+				_ = d.cancelCompositeObservation(ctx, o)
+				return
+			}
+			_ = o.o.Cancel(ctx)
 		}()
 	}
 	return nil
 }
 
-func (d *Device) processObservation(k node.Path) (mux.Observation, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(d.Lifetime)*time.Second)
-	defer cancel()
+func (d *Device) processObservation(ctx context.Context, k node.Path) (mux.Observation, error) {
 	return d.conn.Observe(ctx, k.String(), func(notification *pool.Message) {
 		if notification.Body() == nil {
 			return
 		}
+		d.MarkAwake()
 		nodes, err := node.DecodeMessage(k, notification)
 		if err != nil {
-			fmt.Printf("decode error %v\n", err)
+			d.count(func(s *NotificationStats) { s.DroppedInvalid++ })
 			return
 		}
-		d.obsChan <- observationEvent{
-			p: k,
-			n: nodes,
-		}
+		meta := d.newNotification(notification)
+		meta.Path = k
+		d.dispatch(observationEvent{
+			key:  k,
+			p:    k,
+			n:    nodes,
+			meta: meta,
+		})
 	}, d.acceptOption)
 }
 
@@ -201,7 +308,7 @@ func (d *Device) String() string {
 
 func (d *Device) Close() {
 	d.observations.Range(func(key, value any) bool {
-		_ = d.CancelObserve(key.(node.Path))
+		_ = d.cancelObservation(key)
 		return true
 	})
 	d.cancel()
@@ -209,21 +316,77 @@ func (d *Device) Close() {
 
 func (d *Device) initOrUpdateObservation() {
 	d.observations.Range(func(key, value any) bool {
-		k := key.(node.Path)
 		v := value.(Observation)
-		if v.o == nil || v.o.Canceled() {
-			no, err := d.processObservation(k)
-			if err == nil {
-				v.o = no
-				d.observations.Store(key, v)
-			}
+		if !v.pending && (v.o == nil || v.o.Canceled()) {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Duration(d.Lifetime)*time.Second)
+			_ = d.establishObservation(ctx, key)
+			cancel()
 		}
 		return true
 	})
+	d.subs.lock.Lock()
+	d.pruneObservations()
+	d.subs.lock.Unlock()
+}
+
+// claimObservation mark the observation of key pending if it is neither
+// established nor pending, so that a single observe request is sent for it
+func (d *Device) claimObservation(key any) (Observation, bool) {
+	d.subs.lock.Lock()
+	defer d.subs.lock.Unlock()
+	val, ok := d.observations.Load(key)
+	if !ok {
+		return Observation{}, false
+	}
+	v := val.(Observation)
+	if v.pending || (v.o != nil && !v.o.Canceled()) {
+		return v, false
+	}
+	v.pending = true
+	d.observations.Store(key, v)
+	return v, true
+}
+
+// establishObservation send the observe request of the observation of key
+// and store the established observation, nothing is sent if it is pending
+// or established
+func (d *Device) establishObservation(ctx context.Context, key any) error {
+	v, ok := d.claimObservation(key)
+	if !ok {
+		return nil
+	}
+	var no mux.Observation
+	var err error
+	if len(v.paths) > 0 {
+		no, err = d.processCompositeObservation(ctx, key, v.paths)
+	} else {
+		no, err = d.processObservation(ctx, key.(node.Path))
+	}
+	d.subs.lock.Lock()
+	defer d.subs.lock.Unlock()
+	val, stored := d.observations.Load(key)
+	if stored {
+		v = val.(Observation)
+	}
+	v.pending = false
+	if err != nil {
+		if stored {
+			d.observations.Store(key, v)
+		}
+		return err
+	}
+	v.o = no
+	d.observations.Store(key, v)
+	if !stored {
+		// canceled while the request was in flight
+		return d.cancelObservation(key)
+	}
+	return nil
 }
 
 func (d *Device) run() {
 	<-time.After(time.Second)
+	d.restoreObservations()
 	d.initOrUpdateObservation()
 	for {
 		select {
@@ -232,26 +395,10 @@ func (d *Device) run() {
 		case <-d.ctx.Done():
 			d.Close()
 			return
-		case e := <-d.obsChan:
-
-			val, ok := d.observations.Load(e.p)
-			if ok {
-				o := val.(Observation)
-				go func() {
-					o.cb(d, e.p, e.n)
-					d.updateState(e.p, e.n)
-				}()
-			}
 		}
 	}
 }
 
-// updateState update Device stage storage
-func (d *Device) updateState(k node.Path, m []node.Node) {
-	d.objLock.Lock()
-	defer d.objLock.Unlock()
-}
-
 func (d *Device) HasObject(id uint16) bool {
 	d.objLock.RLock()
 	defer d.objLock.RUnlock()
@@ -287,10 +434,18 @@ func (d *Device) Read(ctx context.Context, p node.Path) ([]node.Node, error) {
 	if err != nil {
 		return nil, err
 	}
+	if msg.Code() != codes.Content {
+		return nil, NewResponseError("read", p.String(), msg)
+	}
 	if msg.Body() == nil {
 		return nil, ErrEmptyBody
 	}
-	return node.DecodeMessage(p, msg)
+	nodes, err := node.DecodeMessage(p, msg)
+	if err != nil {
+		return nil, err
+	}
+	d.UpdateState(p, nodes, StateRead)
+	return nodes, nil
 }
 
 func (d *Device) ReadObject(ctx context.Context, p node.Path) (*node.Object, error) {
@@ -309,21 +464,110 @@ func (d *Device) ReadResource(ctx context.Context, p node.Path) (*node.Resource,
 	return node.GetResourceByPath(nodes, p)
 }
 
+// WriteMode select how a Write operation apply the new values
+type WriteMode int
+
+const (
+	// WriteReplace replace the target with the new values, a PUT request,
+	// resources or instances missing from the values are removed
+	WriteReplace WriteMode = iota
+	// WritePartialUpdate update or add the given values only, a POST
+	// request to an object instance or a multiple resource
+	WritePartialUpdate
+)
+
+func (m WriteMode) String() string {
+	switch m {
+	case WriteReplace:
+		return "Replace"
+	case WritePartialUpdate:
+		return "PartialUpdate"
+	default:
+		return "Unknow"
+	}
+}
+
+// Write replace p with val, see WriteWithMode
 func (d *Device) Write(ctx context.Context, p node.Path, val ...node.Node) error {
+	return d.WriteWithMode(ctx, p, WriteReplace, val...)
+}
+
+// WriteWithMode write val to p, p may be an object instance, a resource or
+// a resource instance path for WriteReplace, an object instance or a
+// resource path for WritePartialUpdate
+func (d *Device) WriteWithMode(ctx context.Context, p node.Path, mode WriteMode, val ...node.Node) error {
+	switch mode {
+	case WriteReplace:
+		if !p.IsObjectInstance() && !p.IsResource() && !p.IsResourceInstance() {
+			return node.ErrPathInvalidValue
+		}
+	case WritePartialUpdate:
+		if !p.IsObjectInstance() && !p.IsResource() {
+			return node.ErrPathInvalidValue
+		}
+	default:
+		return fmt.Errorf("write mode %v: %w", mode, node.ErrPathInvalidValue)
+	}
 	msg, err := node.EncodeMessage(d.writeMediaType, val)
 	if err != nil {
 		return err
 	}
-	resp, err := d.conn.Put(ctx, p.String(), d.writeMediaType, msg, d.acceptOption)
+	var resp *pool.Message
+	op := "write"
+	if mode == WritePartialUpdate {
+		op = "partial update"
+		resp, err = d.conn.Post(ctx, p.String(), d.writeMediaType, msg, d.acceptOption)
+	} else {
+		resp, err = d.conn.Put(ctx, p.String(), d.writeMediaType, msg, d.acceptOption)
+	}
 	if err != nil {
 		return err
 	}
 	if resp.Code() != codes.Changed {
-		return ErrUnexpectedResponseCode
+		return NewResponseError(op, p.String(), resp)
 	}
 	return nil
 }
 
+// WriteResourceInstance replace the value of the resource instance p of a
+// multiple resource, v is checked against the registry resource type, see
+// node.NewResourceInstanceValue
+func (d *Device) WriteResourceInstance(ctx context.Context, p node.Path, v any) error {
+	if !p.IsResourceInstance() {
+		return node.ErrPathInvalidValue
+	}
+	ri, err := node.NewResourceInstanceValue(p, v)
+	if err != nil {
+		return err
+	}
+	return d.WriteWithMode(ctx, p, WriteReplace, ri)
+}
+
+// AddResourceInstance add the resource instance p to its multiple resource,
+// or update it if it exists, other instances are kept. It is a partial
+// update of the resource
+func (d *Device) AddResourceInstance(ctx context.Context, p node.Path, v any) error {
+	if !p.IsResourceInstance() {
+		return node.ErrPathInvalidValue
+	}
+	ri, err := node.NewResourceInstanceValue(p, v)
+	if err != nil {
+		return err
+	}
+	rp, err := p.Parent()
+	if err != nil {
+		return err
+	}
+	res, err := node.NewResource(rp, true)
+	if err != nil {
+		return err
+	}
+	if err = res.SetInstance(ri); err != nil {
+		return err
+	}
+	return d.WriteWithMode(ctx, rp, WritePartialUpdate, res)
+}
+
 func (d *Device) WriteResource(ctx context.Context, p node.Path, val *node.Resource) error {
 	return d.Write(ctx, p, val)
 }
@@ -342,58 +586,231 @@ func (d *Device) Discover(ctx context.Context, p node.Path) ([]*encoding.CoreLin
 	if err != nil {
 		return nil, err
 	}
+	if r.Code() != codes.Content {
+		return nil, NewResponseError("discover", p.String(), r)
+	}
 	links := make([]*encoding.CoreLink, 0)
 	if r.Body() != nil {
 		if b, err2 := io.ReadAll(r.Body()); err2 == nil {
-			links, _ = encoding.CoreLinksFromString(string(b))
+			return encoding.CoreLinksFromString(string(b))
 		}
 	}
 	return links, nil
 }
 
+// WriteAttributes set the notification attributes of p, attributes marked
+// with Attributes.Clear are removed. The request is a PUT without payload,
+// attributes are sent as URI queries
+func (d *Device) WriteAttributes(ctx context.Context, p node.Path, attrs *node.Attributes) error {
+	if attrs == nil {
+		return node.ErrEmpty
+	}
+	if err := attrs.Validate(p); err != nil {
+		return err
+	}
+	opts := make(message.Options, 0)
+	for _, q := range attrs.Queries() {
+		opts = append(opts, message.Option{ID: message.URIQuery, Value: []byte(q)})
+	}
+	resp, err := d.conn.Put(ctx, p.String(), message.TextPlain, nil, opts...)
+	if err != nil {
+		return err
+	}
+	if resp.Code() != codes.Changed {
+		return NewResponseError("write attributes", p.String(), resp)
+	}
+	return nil
+}
+
+// ObserveWithAttributes write the notification attributes of p and then
+// observe it, the observation is not registered if writing fails
+func (d *Device) ObserveWithAttributes(ctx context.Context, p node.Path, attrs *node.Attributes, onMsg ObserveFunc) error {
+	if err := d.WriteAttributes(ctx, p, attrs); err != nil {
+		return err
+	}
+	_ = d.CancelObserve(p)
+	if d.setObserveFunc(p, withMeta(onMsg), attrs) {
+		return nil
+	}
+	d.observations.Store(p, Observation{
+		o:     nil,
+		cb:    withMeta(onMsg),
+		attrs: attrs,
+	})
+	return nil
+}
+
+// DiscoverTree discover p and return the result as a tree of entries with
+// parsed attributes
+func (d *Device) DiscoverTree(ctx context.Context, p node.Path) (*node.Discovery, error) {
+	links, err := d.Discover(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	return node.NewDiscovery(links)
+}
+
+// Execute execute the resource p, arguments must follow the execute
+// arguments syntax, see encoding.ExecuteArgs. The request is not sent if
+// the registry define the resource as not executable
 func (d *Device) Execute(ctx context.Context, p node.Path, arguments string) error {
 	if !p.IsResource() {
 		return node.ErrPathInvalidValue
 	}
+	if _, err := encoding.ParseExecuteArgs(arguments); err != nil {
+		return err
+	}
+	if err := node.GetRegistry().CheckOperation(p, node.OP_E); err != nil {
+		return err
+	}
 	// TextPlain indicates arguments type
 	resp, err := d.conn.Post(ctx, p.String(), message.TextPlain, bytes.NewReader([]byte(arguments)))
 	if err != nil {
 		return err
 	}
 	if resp.Code() != codes.Changed {
-		return ErrUnexpectedResponseCode
+		return NewResponseError("execute", p.String(), resp)
 	}
 	return nil
 }
 
-func (d *Device) Create(ctx context.Context, p node.Path, val *node.ObjectInstance) error {
+// ExecuteWithArgs execute the resource p with args, see Execute
+func (d *Device) ExecuteWithArgs(ctx context.Context, p node.Path, args *encoding.ExecuteArgs) error {
+	if args == nil {
+		return d.Execute(ctx, p, "")
+	}
+	return d.Execute(ctx, p, args.String())
+}
+
+// Create create the object instances vals of the object p with one request,
+// instance ids are chosen by the server and encoded in the payload.
+// The paths of the created instances are returned, the Location-Path of
+// the response is used if the client report one
+func (d *Device) Create(ctx context.Context, p node.Path, vals ...*node.ObjectInstance) ([]node.Path, error) {
 	if !p.IsObject() {
-		return node.ErrPathInvalidValue
+		return nil, node.ErrPathInvalidValue
+	}
+	if len(vals) == 0 {
+		return nil, node.ErrEmpty
 	}
-	msg, err := node.EncodeMessage(d.writeMediaType, []node.Node{val})
+	oid, _ := p.ObjectId()
+	obj := node.NewObject(oid)
+	for _, v := range vals {
+		obj.Instances[v.Id] = v
+	}
+	msg, err := node.EncodeMessage(d.writeMediaType, []node.Node{obj})
 	if err != nil {
-		return err
+		return nil, err
+	}
+	paths, err := d.create(ctx, p, msg)
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		for _, v := range vals {
+			paths = append(paths, node.NewObjectInstancePath(oid, v.Id))
+		}
+	}
+	d.addObjectInstances(paths)
+	return paths, nil
+}
+
+// CreateInstance create an object instance of the object p with the
+// resources res, the instance id is chosen by the client and returned from
+// the Location-Path of the response.
+//
+// Resources are encoded without object instance, which is only defined for
+// TLV, node.ErrContentFormatNotSupport is returned for other write media types
+func (d *Device) CreateInstance(ctx context.Context, p node.Path, res ...*node.Resource) (node.Path, error) {
+	if !p.IsObject() {
+		return node.Path{}, node.ErrPathInvalidValue
+	}
+	if d.writeMediaType != message.AppLwm2mTLV {
+		return node.Path{}, node.ErrContentFormatNotSupport
+	}
+	var msg io.ReadSeeker
+	if len(res) > 0 {
+		nodes := make([]node.Node, 0, len(res))
+		for _, r := range res {
+			nodes = append(nodes, r)
+		}
+		var err error
+		if msg, err = node.EncodeMessage(d.writeMediaType, nodes); err != nil {
+			return node.Path{}, err
+		}
+	}
+	paths, err := d.create(ctx, p, msg)
+	if err != nil {
+		return node.Path{}, err
 	}
+	if len(paths) == 0 {
+		return node.Path{}, ErrNoLocationPath
+	}
+	d.addObjectInstances(paths)
+	return paths[0], nil
+}
+
+// create send the Create request and return the object instance path of
+// the Location-Path option, if any
+func (d *Device) create(ctx context.Context, p node.Path, msg io.ReadSeeker) ([]node.Path, error) {
 	resp, err := d.conn.Post(ctx, p.String(), d.writeMediaType, msg, d.acceptOption)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if resp.Code() != codes.Created {
-		return ErrUnexpectedResponseCode
+		return nil, NewResponseError("create", p.String(), resp)
 	}
-	return nil
+	paths := make([]node.Path, 0, 1)
+	if lp, err := resp.Options().LocationPath(); err == nil && lp != "" {
+		ip, err := node.NewPathFromString(lp)
+		if err != nil || !ip.IsObjectInstance() || !ip.IsChildOfOrEq(p) {
+			return nil, fmt.Errorf("location path %q: %w", lp, node.ErrPathInvalidValue)
+		}
+		paths = append(paths, ip)
+	}
+	return paths, nil
 }
 
+// addObjectInstances add the object instance paths to the object list of
+// the device
+func (d *Device) addObjectInstances(paths []node.Path) {
+	d.objLock.Lock()
+	defer d.objLock.Unlock()
+	for _, p := range paths {
+		oid, _ := p.ObjectId()
+		iid, _ := p.ObjectInstanceId()
+		obj, ok := d.objs[oid]
+		if !ok {
+			obj = node.NewObject(oid)
+			d.objs[oid] = obj
+		}
+		if _, ok = obj.Instances[iid]; !ok {
+			obj.Instances[iid] = node.NewObjectInstance(iid)
+		}
+	}
+}
+
+// Delete delete the object instance p, or the resource instance p of a
+// multiple resource
 func (d *Device) Delete(ctx context.Context, p node.Path) error {
-	if !p.IsObjectInstance() {
+	if !p.IsObjectInstance() && !p.IsResourceInstance() {
 		return node.ErrPathInvalidValue
 	}
 	resp, err := d.conn.Delete(ctx, p.String(), d.acceptOption)
 	if err != nil {
 		return err
 	}
-	if resp.Code() != codes.DELETE {
-		return ErrUnexpectedResponseCode
+	if resp.Code() != codes.Deleted {
+		return NewResponseError("delete", p.String(), resp)
+	}
+	if p.IsObjectInstance() {
+		oid, _ := p.ObjectId()
+		iid, _ := p.ObjectInstanceId()
+		d.objLock.Lock()
+		if obj, ok := d.objs[oid]; ok {
+			delete(obj.Instances, iid)
+		}
+		d.objLock.Unlock()
 	}
 	return nil
 }
@@ -0,0 +1,288 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/plgd-dev/go-coap/v3/message"
+	"github.com/plgd-dev/go-coap/v3/message/codes"
+	"github.com/plgd-dev/go-coap/v3/mux"
+	coapNet "github.com/plgd-dev/go-coap/v3/net"
+	"github.com/plgd-dev/go-coap/v3/options"
+	"github.com/plgd-dev/go-coap/v3/udp"
+	"github.com/stretchr/testify/assert"
+	"github.com/yplam/lwm2m/encoding"
+	"github.com/yplam/lwm2m/node"
+)
+
+// newTestDevice start a fake LWM2M client that serve requests with h, and
+// return a Device connected to it
+func newTestDevice(t *testing.T, h mux.HandlerFunc) *Device {
+	l, err := coapNet.NewListenUDP("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := mux.NewRouter()
+	router.DefaultHandleFunc(h)
+	s := udp.NewServer(options.WithMux(router))
+	go func() {
+		_ = s.Serve(l)
+	}()
+	conn, err := udp.Dial(l.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &Device{
+		ctx:       ctx,
+		cancel:    cancel,
+		Id:        "test",
+		Endpoint:  "test",
+		Lifetime:  60,
+		objs:      make(map[uint16]*node.Object),
+		states:    make(map[node.Path]ResourceState),
+		notifiers: make(map[any]*notifier),
+	}
+	d.subs = newSubscriptions(d)
+	d.setConn(conn)
+	d.SetMediaTypes(DefaultMediaType, DefaultMediaType)
+	t.Cleanup(func() {
+		cancel()
+		_ = conn.Close()
+		s.Stop()
+		_ = l.Close()
+	})
+	return d
+}
+
+func testContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+// testRequest is a request received by the fake client
+type testRequest struct {
+	code    codes.Code
+	path    string
+	format  message.MediaType
+	queries []string
+	body    []byte
+	// observe is the Observe option value, -1 if absent
+	observe int
+	token   message.Token
+}
+
+func newTestRequest(r *mux.Message) testRequest {
+	req := testRequest{code: r.Code(), format: message.MediaType(65535), observe: -1, token: r.Token()}
+	if obs, err := r.Observe(); err == nil {
+		req.observe = int(obs)
+	}
+	req.path, _ = r.Options().Path()
+	if f, err := r.ContentFormat(); err == nil {
+		req.format = f
+	}
+	req.queries, _ = r.Options().Queries()
+	if r.Body() != nil {
+		req.body, _ = io.ReadAll(r.Body())
+	}
+	return req
+}
+
+func TestWriteWithMode(t *testing.T) {
+	reqs := make(chan testRequest, 1)
+	d := newTestDevice(t, func(w mux.ResponseWriter, r *mux.Message) {
+		reqs <- newTestRequest(r)
+		_ = w.SetResponse(codes.Changed, message.TextPlain, nil)
+	})
+	d.SetMediaTypes(message.AppSenmlJSON, message.AppSenmlJSON)
+	oi := node.NewObjectInstance(0)
+	res, err := node.NewSingleResourceValue(node.NewResourcePath(1, 0, 1), 300)
+	assert.Nil(t, err)
+	oi.SetResource(1, res)
+
+	err = d.WriteWithMode(testContext(t), node.NewObjectInstancePath(1, 0), WritePartialUpdate, oi)
+	assert.Nil(t, err)
+	req := <-reqs
+	assert.Equal(t, codes.POST, req.code)
+	assert.Equal(t, "/1/0", req.path)
+	assert.Equal(t, `[{"n":"/1/0/1","v":300}]`, string(req.body))
+
+	err = d.Write(testContext(t), node.NewObjectInstancePath(1, 0), oi)
+	assert.Nil(t, err)
+	req = <-reqs
+	assert.Equal(t, codes.PUT, req.code)
+
+	err = d.WriteWithMode(testContext(t), node.NewObjectPath(1), WriteReplace, oi)
+	assert.Equal(t, node.ErrPathInvalidValue, err)
+	err = d.WriteWithMode(testContext(t), node.NewResourceInstancePath(1, 0, 1, 0), WritePartialUpdate, oi)
+	assert.Equal(t, node.ErrPathInvalidValue, err)
+	assert.Equal(t, 0, len(reqs))
+}
+
+func TestResourceInstance(t *testing.T) {
+	reqs := make(chan testRequest, 1)
+	d := newTestDevice(t, func(w mux.ResponseWriter, r *mux.Message) {
+		reqs <- newTestRequest(r)
+		if r.Code() == codes.DELETE {
+			_ = w.SetResponse(codes.Deleted, message.TextPlain, nil)
+			return
+		}
+		_ = w.SetResponse(codes.Changed, message.TextPlain, nil)
+	})
+	p := node.NewResourceInstancePath(3, 0, 7, 1)
+
+	d.SetMediaTypes(message.TextPlain, message.TextPlain)
+	assert.Nil(t, d.WriteResourceInstance(testContext(t), p, 5000))
+	req := <-reqs
+	assert.Equal(t, codes.PUT, req.code)
+	assert.Equal(t, "/3/0/7/1", req.path)
+	assert.Equal(t, "5000", string(req.body))
+
+	d.SetMediaTypes(message.AppSenmlJSON, message.AppSenmlJSON)
+	assert.Nil(t, d.AddResourceInstance(testContext(t), p, 5000))
+	req = <-reqs
+	assert.Equal(t, codes.POST, req.code)
+	assert.Equal(t, "/3/0/7", req.path)
+	assert.Equal(t, `[{"n":"/3/0/7/1","v":5000}]`, string(req.body))
+
+	assert.Nil(t, d.Delete(testContext(t), p))
+	req = <-reqs
+	assert.Equal(t, codes.DELETE, req.code)
+	assert.Equal(t, "/3/0/7/1", req.path)
+
+	assert.ErrorIs(t, d.WriteResourceInstance(testContext(t), p, "5000"), node.ErrResourceType)
+	assert.Equal(t, node.ErrPathInvalidValue, d.AddResourceInstance(testContext(t), node.NewResourcePath(3, 0, 7), 5000))
+	assert.Equal(t, 0, len(reqs))
+}
+
+func TestCreate(t *testing.T) {
+	reqs := make(chan testRequest, 1)
+	location := make(chan []string, 1)
+	d := newTestDevice(t, func(w mux.ResponseWriter, r *mux.Message) {
+		reqs <- newTestRequest(r)
+		opts := make(message.Options, 0)
+		for _, s := range <-location {
+			opts = append(opts, message.Option{ID: message.LocationPath, Value: []byte(s)})
+		}
+		_ = w.SetResponse(codes.Created, message.TextPlain, nil, opts...)
+	})
+	d.SetMediaTypes(message.AppSenmlJSON, message.AppSenmlJSON)
+	newInstance := func(iid uint16, v string) *node.ObjectInstance {
+		oi := node.NewObjectInstance(iid)
+		res, err := node.NewSingleResourceValue(node.NewResourcePath(3303, iid, 5750), v)
+		assert.Nil(t, err)
+		oi.SetResource(5750, res)
+		return oi
+	}
+
+	// several instances with server chosen ids
+	location <- nil
+	paths, err := d.Create(testContext(t), node.NewObjectPath(3303), newInstance(1, "a"), newInstance(2, "b"))
+	assert.Nil(t, err)
+	req := <-reqs
+	assert.Equal(t, codes.POST, req.code)
+	assert.Equal(t, "/3303", req.path)
+	assert.Equal(t, `[{"n":"/3303/1/5750","vs":"a"},{"n":"/3303/2/5750","vs":"b"}]`, string(req.body))
+	assert.Equal(t, []node.Path{node.NewObjectInstancePath(3303, 1), node.NewObjectInstancePath(3303, 2)}, paths)
+	assert.True(t, d.HasObjectInstance(3303, 1))
+	assert.True(t, d.HasObjectInstance(3303, 2))
+
+	location <- []string{"3303", "4"}
+	paths, err = d.Create(testContext(t), node.NewObjectPath(3303), newInstance(3, "c"))
+	assert.Nil(t, err)
+	<-reqs
+	assert.Equal(t, []node.Path{node.NewObjectInstancePath(3303, 4)}, paths)
+	assert.True(t, d.HasObjectInstance(3303, 4))
+
+	// client chosen id
+	res, err := node.NewSingleResourceValue(node.NewResourcePath(3303, 0, 5750), "d")
+	assert.Nil(t, err)
+	_, err = d.CreateInstance(testContext(t), node.NewObjectPath(3303), res)
+	assert.Equal(t, node.ErrContentFormatNotSupport, err)
+	d.SetMediaTypes(message.AppLwm2mTLV, message.AppLwm2mTLV)
+	location <- []string{"3303", "5"}
+	p, err := d.CreateInstance(testContext(t), node.NewObjectPath(3303), res)
+	assert.Nil(t, err)
+	req = <-reqs
+	assert.Equal(t, message.AppLwm2mTLV, req.format)
+	assert.Equal(t, []byte{0xe1, 0x16, 0x76, 'd'}, req.body[:4])
+	assert.Equal(t, node.NewObjectInstancePath(3303, 5), p)
+	assert.True(t, d.HasObjectInstance(3303, 5))
+
+	location <- nil
+	_, err = d.CreateInstance(testContext(t), node.NewObjectPath(3303), res)
+	<-reqs
+	assert.Equal(t, ErrNoLocationPath, err)
+	location <- []string{"3304", "1"}
+	_, err = d.CreateInstance(testContext(t), node.NewObjectPath(3303), res)
+	<-reqs
+	assert.ErrorIs(t, err, node.ErrPathInvalidValue)
+}
+
+func TestExecute(t *testing.T) {
+	reqs := make(chan testRequest, 1)
+	d := newTestDevice(t, func(w mux.ResponseWriter, r *mux.Message) {
+		reqs <- newTestRequest(r)
+		_ = w.SetResponse(codes.Changed, message.TextPlain, nil)
+	})
+	args := encoding.NewExecuteArgs()
+	assert.Nil(t, args.Set(0, "foo"))
+	assert.Nil(t, args.SetFlag(1))
+	assert.Nil(t, d.ExecuteWithArgs(testContext(t), node.NewResourcePath(3, 0, 4), args))
+	req := <-reqs
+	assert.Equal(t, codes.POST, req.code)
+	assert.Equal(t, "/3/0/4", req.path)
+	assert.Equal(t, `0='foo',1`, string(req.body))
+
+	// checked before sending
+	err := d.Execute(testContext(t), node.NewResourcePath(3, 0, 0), "")
+	assert.ErrorIs(t, err, node.ErrOperationNotAllowed)
+	err = d.Execute(testContext(t), node.NewResourcePath(3, 0, 4), "0=foo")
+	assert.ErrorIs(t, err, encoding.ErrExecuteArgsInvalidValue)
+	assert.Equal(t, 0, len(reqs))
+}
+
+func TestWriteAttributesNil(t *testing.T) {
+	reqs := make(chan testRequest, 1)
+	d := newTestDevice(t, func(w mux.ResponseWriter, r *mux.Message) {
+		reqs <- newTestRequest(r)
+		_ = w.SetResponse(codes.Changed, message.TextPlain, nil)
+	})
+	p := node.NewResourcePath(3, 0, 9)
+	assert.Equal(t, node.ErrEmpty, d.WriteAttributes(testContext(t), p, nil))
+	err := d.ObserveWithAttributes(testContext(t), p, nil, func(d *Device, p node.Path, notify []node.Node) {})
+	assert.Equal(t, node.ErrEmpty, err)
+	_, ok := d.observations.Load(p)
+	assert.False(t, ok)
+	assert.Equal(t, 0, len(reqs))
+}
+
+func TestResponseError(t *testing.T) {
+	d := newTestDevice(t, func(w mux.ResponseWriter, r *mux.Message) {
+		if r.Code() == codes.GET {
+			_ = w.SetResponse(codes.NotFound, message.TextPlain, nil)
+			return
+		}
+		_ = w.SetResponse(codes.MethodNotAllowed, message.TextPlain, bytes.NewReader([]byte("read only")))
+	})
+	_, err := d.Read(testContext(t), node.NewObjectInstancePath(3, 0))
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.ErrorIs(t, err, ErrUnexpectedResponseCode)
+	assert.False(t, errors.Is(err, ErrMethodNotAllowed))
+
+	err = d.WriteResourceInstance(testContext(t), node.NewResourceInstancePath(3, 0, 7, 1), 5000)
+	assert.ErrorIs(t, err, ErrMethodNotAllowed)
+	var re *ResponseError
+	if assert.ErrorAs(t, err, &re) {
+		assert.Equal(t, "write", re.Op)
+		assert.Equal(t, "/3/0/7/1", re.Path)
+		assert.Equal(t, codes.MethodNotAllowed, re.Code)
+		assert.Equal(t, "read only", re.Diagnostic)
+	}
+	assert.Equal(t, "write /3/0/7/1: MethodNotAllowed: read only", err.Error())
+}
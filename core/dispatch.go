@@ -0,0 +1,177 @@
+package core
+
+import (
+	"sync"
+)
+
+// OverflowPolicy select what happen to a notification received while the
+// notification queue of its observation is full
+type OverflowPolicy int
+
+const (
+	// OverflowDropOldest drop the oldest queued notification
+	OverflowDropOldest OverflowPolicy = iota
+	// OverflowDropNewest drop the received notification
+	OverflowDropNewest
+	// OverflowBlock block the connection until the queue has room
+	OverflowBlock
+)
+
+func (p OverflowPolicy) String() string {
+	switch p {
+	case OverflowDropOldest:
+		return "DropOldest"
+	case OverflowDropNewest:
+		return "DropNewest"
+	case OverflowBlock:
+		return "Block"
+	default:
+		return "Unknow"
+	}
+}
+
+// DefaultNotificationQueueSize is the number of notifications queued per
+// observation while its callback is running
+const DefaultNotificationQueueSize = 16
+
+// NotificationStats count the notifications of a device. Notifications
+// older than the previous one of their observation, RFC7641 3.4, are
+// dropped by go-coap before reaching the device and are not counted
+type NotificationStats struct {
+	Delivered uint64
+	// DroppedOverflow is the number of notifications dropped by the
+	// overflow policy
+	DroppedOverflow uint64
+	// DroppedInvalid is the number of notifications whose payload can not
+	// be decoded
+	DroppedInvalid uint64
+}
+
+// notifier deliver the notifications of one observation in order, from a
+// bounded queue
+type notifier struct {
+	lock sync.Mutex
+	ch   chan observationEvent
+	done chan struct{}
+}
+
+// SetNotificationQueue set the notification queue size of each observation
+// and the overflow policy, it apply to observations notified afterwards
+func (d *Device) SetNotificationQueue(size int, policy OverflowPolicy) {
+	d.notifyLock.Lock()
+	defer d.notifyLock.Unlock()
+	d.queueSize = size
+	d.overflow = policy
+}
+
+// NotificationStats return the notification counters of the device
+func (d *Device) NotificationStats() NotificationStats {
+	d.notifyLock.Lock()
+	defer d.notifyLock.Unlock()
+	return d.stats
+}
+
+// getNotifier return the notifier of key, it is started if not exists
+func (d *Device) getNotifier(key any) *notifier {
+	d.notifyLock.Lock()
+	defer d.notifyLock.Unlock()
+	if n, ok := d.notifiers[key]; ok {
+		return n
+	}
+	size := d.queueSize
+	if size <= 0 {
+		size = DefaultNotificationQueueSize
+	}
+	n := &notifier{
+		ch:   make(chan observationEvent, size),
+		done: make(chan struct{}),
+	}
+	d.notifiers[key] = n
+	go d.runNotifier(n)
+	return n
+}
+
+// stopNotifier stop the notifier of key, queued notifications are dropped
+func (d *Device) stopNotifier(key any) {
+	d.notifyLock.Lock()
+	defer d.notifyLock.Unlock()
+	if n, ok := d.notifiers[key]; ok {
+		delete(d.notifiers, key)
+		close(n.done)
+	}
+}
+
+func (d *Device) count(f func(s *NotificationStats)) {
+	d.notifyLock.Lock()
+	defer d.notifyLock.Unlock()
+	f(&d.stats)
+}
+
+// dispatch queue the notification e to the notifier of its observation,
+// it does not block unless the overflow policy is OverflowBlock.
+// Observe and composite observations are both created by go-coap, which
+// only call back with notifications fresher than the previous one, so e is
+// not checked again here
+func (d *Device) dispatch(e observationEvent) {
+	if _, ok := d.observations.Load(e.key); !ok {
+		return
+	}
+	n := d.getNotifier(e.key)
+	d.notifyLock.Lock()
+	policy := d.overflow
+	d.notifyLock.Unlock()
+
+	n.lock.Lock()
+	switch policy {
+	case OverflowBlock:
+		n.lock.Unlock()
+		select {
+		case n.ch <- e:
+		case <-n.done:
+		}
+		return
+	case OverflowDropNewest:
+		select {
+		case n.ch <- e:
+		default:
+			d.count(func(s *NotificationStats) { s.DroppedOverflow++ })
+		}
+	default:
+		for sent := false; !sent; {
+			select {
+			case n.ch <- e:
+				sent = true
+			default:
+				select {
+				case <-n.ch:
+					d.count(func(s *NotificationStats) { s.DroppedOverflow++ })
+				default:
+				}
+			}
+		}
+	}
+	n.lock.Unlock()
+}
+
+func (d *Device) runNotifier(n *notifier) {
+	for {
+		select {
+		case <-n.done:
+			return
+		case <-d.ctx.Done():
+			return
+		case e := <-n.ch:
+			val, ok := d.observations.Load(e.key)
+			if !ok {
+				continue
+			}
+			o := val.(Observation)
+			d.UpdateState(e.p, e.n, StateNotify)
+			if o.cb != nil {
+				o.cb(d, e.p, e.n, e.meta)
+			}
+			d.notifySubscribers(e.key, e.n, e.meta)
+			d.count(func(s *NotificationStats) { s.Delivered++ })
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package core
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/plgd-dev/go-coap/v3/message"
+	"github.com/plgd-dev/go-coap/v3/message/codes"
+	"github.com/plgd-dev/go-coap/v3/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/yplam/lwm2m/node"
+)
+
+// testEvent return a notification of the resource p with seq as value, so
+// that the callback can tell which notification it got
+func testEvent(p node.Path, seq uint32, t time.Time) observationEvent {
+	res, _ := node.NewSingleResourceValue(p, int64(seq))
+	return observationEvent{key: p, p: p, n: []node.Node{res}, meta: &Notification{
+		Path:        p,
+		Sequence:    seq,
+		HasSequence: true,
+		Time:        t,
+	}}
+}
+
+func testEventSeq(notify []node.Node) uint32 {
+	ins, _ := notify[0].(*node.Resource).GetInstance(0)
+	return uint32(ins.Value().(int64))
+}
+
+func TestDispatch(t *testing.T) {
+	d := newTestDevice(t, func(w mux.ResponseWriter, r *mux.Message) {})
+	p := node.NewResourcePath(3, 0, 9)
+	release := make(chan struct{})
+	seqs := make(chan uint32, 10)
+	d.observations.Store(p, Observation{
+		cb: withMeta(func(d *Device, p node.Path, notify []node.Node) {
+			<-release
+			seqs <- testEventSeq(notify)
+		}),
+	})
+	d.SetNotificationQueue(2, OverflowDropOldest)
+
+	now := time.Now()
+	d.dispatch(testEvent(p, 1, now))
+	// wait for the worker to take the first notification
+	assert.Eventually(t, func() bool {
+		return len(d.getNotifier(p).ch) == 0
+	}, time.Second, time.Millisecond)
+	d.dispatch(testEvent(p, 2, now))
+	d.dispatch(testEvent(p, 3, now))
+	d.dispatch(testEvent(p, 4, now))
+	close(release)
+
+	// 2 is dropped when 4 is received
+	for _, want := range []uint32{1, 3, 4} {
+		select {
+		case seq := <-seqs:
+			assert.Equal(t, want, seq)
+		case <-testContext(t).Done():
+			t.Fatal("no notification")
+		}
+	}
+	assert.Eventually(t, func() bool {
+		return d.NotificationStats().Delivered == 3
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, NotificationStats{
+		Delivered:       3,
+		DroppedOverflow: 1,
+	}, d.NotificationStats())
+}
+
+func TestDispatchOrder(t *testing.T) {
+	d := newTestDevice(t, func(w mux.ResponseWriter, r *mux.Message) {})
+	p := node.NewResourcePath(3, 0, 9)
+	got := make(chan uint32, 10)
+	d.SetNotificationQueue(10, OverflowBlock)
+	now := time.Now()
+	d.observations.Store(p, Observation{
+		cb: withMeta(func(d *Device, p node.Path, notify []node.Node) {
+			got <- testEventSeq(notify)
+		}),
+	})
+	for i := 1; i <= 5; i++ {
+		d.dispatch(testEvent(p, uint32(i), now))
+	}
+	for i := 1; i <= 5; i++ {
+		assert.Equal(t, uint32(i), <-got)
+	}
+
+	// sequence numbers are checked by go-coap, not by dispatch
+	d.dispatch(testEvent(p, 0, now))
+	assert.Equal(t, uint32(0), <-got)
+
+	// notifications of a canceled observation are not delivered
+	assert.Nil(t, d.CancelObserve(p))
+	d.dispatch(testEvent(p, 1, now))
+	select {
+	case <-got:
+		t.Fatal("notification delivered after cancel")
+	case <-time.After(50 * time.Millisecond):
+	}
+	assert.Equal(t, uint64(6), d.NotificationStats().Delivered)
+}
+
+func TestObserveSyncFirstNotification(t *testing.T) {
+	d := newTestDevice(t, func(w mux.ResponseWriter, r *mux.Message) {
+		_ = w.SetResponse(codes.Content, message.AppSenmlJSON, bytes.NewReader([]byte(
+			`[{"bn":"/3/0/9","v":95}]`)))
+		if newTestRequest(r).observe == 0 {
+			w.Message().SetObserve(2)
+		}
+	})
+	d.SetMediaTypes(message.AppSenmlJSON, message.AppSenmlJSON)
+	got := make(chan []node.Node, 1)
+	err := d.ObserveSync(node.NewResourcePath(3, 0, 9), func(d *Device, p node.Path, notify []node.Node) {
+		got <- notify
+	})
+	assert.Nil(t, err)
+	select {
+	case notify := <-got:
+		assert.Equal(t, 1, len(notify))
+	case <-time.After(5 * time.Second):
+		t.Fatal("first notification not delivered")
+	}
+}
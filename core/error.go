@@ -0,0 +1,86 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"github.com/plgd-dev/go-coap/v3/message/codes"
+	"github.com/plgd-dev/go-coap/v3/message/pool"
+	"io"
+)
+
+// Errors of the CoAP response codes a LWM2M client may return, a
+// ResponseError matches the one of its code with errors.Is
+var (
+	ErrBadRequest              = errors.New("bad request")
+	ErrUnauthorized            = errors.New("unauthorized")
+	ErrBadOption               = errors.New("bad option")
+	ErrForbidden               = errors.New("forbidden")
+	ErrMethodNotAllowed        = errors.New("method not allowed")
+	ErrNotAcceptable           = errors.New("not acceptable")
+	ErrRequestEntityIncomplete = errors.New("request entity incomplete")
+	ErrPreconditionFailed      = errors.New("precondition failed")
+	ErrRequestEntityTooLarge   = errors.New("request entity too large")
+	ErrUnsupportedMediaType    = errors.New("unsupported media type")
+	ErrInternalServerError     = errors.New("internal server error")
+	ErrServiceUnavailable      = errors.New("service unavailable")
+)
+
+var responseCodeErrors = map[codes.Code]error{
+	codes.BadRequest:              ErrBadRequest,
+	codes.Unauthorized:            ErrUnauthorized,
+	codes.BadOption:               ErrBadOption,
+	codes.Forbidden:               ErrForbidden,
+	codes.NotFound:                ErrNotFound,
+	codes.MethodNotAllowed:        ErrMethodNotAllowed,
+	codes.NotAcceptable:           ErrNotAcceptable,
+	codes.RequestEntityIncomplete: ErrRequestEntityIncomplete,
+	codes.PreconditionFailed:      ErrPreconditionFailed,
+	codes.RequestEntityTooLarge:   ErrRequestEntityTooLarge,
+	codes.UnsupportedMediaType:    ErrUnsupportedMediaType,
+	codes.InternalServerError:     ErrInternalServerError,
+	codes.ServiceUnavailable:      ErrServiceUnavailable,
+}
+
+// ResponseError is returned by device operations when the client answer
+// with an unexpected code. It matches ErrUnexpectedResponseCode and the
+// error of its code, e.g. ErrNotFound for 4.04, with errors.Is
+type ResponseError struct {
+	// Op is the operation name, e.g. "read"
+	Op   string
+	Path string
+	Code codes.Code
+	// Diagnostic is the diagnostic payload of the response, if any
+	Diagnostic string
+}
+
+// NewResponseError create a ResponseError from resp, the body of resp is
+// read as diagnostic text
+func NewResponseError(op string, path string, resp *pool.Message) *ResponseError {
+	e := &ResponseError{
+		Op:   op,
+		Path: path,
+		Code: resp.Code(),
+	}
+	if resp.Body() != nil {
+		if b, err := io.ReadAll(resp.Body()); err == nil {
+			e.Diagnostic = string(b)
+		}
+	}
+	return e
+}
+
+func (e *ResponseError) Error() string {
+	s := fmt.Sprintf("%s %s: %v", e.Op, e.Path, e.Code)
+	if e.Diagnostic != "" {
+		s += ": " + e.Diagnostic
+	}
+	return s
+}
+
+func (e *ResponseError) Is(target error) bool {
+	if target == ErrUnexpectedResponseCode {
+		return true
+	}
+	err, ok := responseCodeErrors[e.Code]
+	return ok && err == target
+}
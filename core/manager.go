@@ -30,6 +30,10 @@ const (
 	DeviceUpdate
 	DevicePostUpdate
 	DeviceDeregister
+	// DeviceObservationsRestored is fired after the observations of a
+	// previous registration of the endpoint are re-issued, see
+	// DeviceEvent.Restored
+	DeviceObservationsRestored
 )
 
 func (e DeviceEventType) String() string {
@@ -44,6 +48,8 @@ func (e DeviceEventType) String() string {
 		return "DevicePostUpdate"
 	case DeviceDeregister:
 		return "DeviceDeregister"
+	case DeviceObservationsRestored:
+		return "DeviceObservationsRestored"
 	default:
 		return "Unknow"
 	}
@@ -52,6 +58,9 @@ func (e DeviceEventType) String() string {
 type DeviceEvent struct {
 	EventType DeviceEventType
 	Device    *Device
+	// Restored is the result of each restored observation of a
+	// DeviceObservationsRestored event
+	Restored []RestoredObservation
 }
 
 // OnDeviceStateChangeFunc call when a device state change
@@ -77,6 +86,11 @@ func NewRegisterRequest(queries []string) (req *RegisterRequest, err error) {
 		SmsNumber:   nil,
 	}
 	for _, val := range queries {
+		// queue mode flag of LWM2M 1.1
+		if val == "Q" {
+			req.Queue = true
+			continue
+		}
 		sps := strings.Split(val, "=")
 		if len(sps) != 2 {
 			continue
@@ -92,6 +106,7 @@ func NewRegisterRequest(queries []string) (req *RegisterRequest, err error) {
 			req.SmsNumber = &sps[1]
 		case "b":
 			req.BindingMode = NewBinding(sps[1])
+			_, req.Queue = ParseBinding(sps[1])
 		default:
 		}
 	}
@@ -107,7 +122,12 @@ func NewRegisterRequest(queries []string) (req *RegisterRequest, err error) {
 type UpdateRequest struct {
 	Lifetime    *int
 	BindingMode *Binding
-	SmsNumber   *string
+	// Queue is set if the update carry the "Q" parameter
+	Queue *bool
+	// BindingQueue is the queue mode of the updated binding, it is only
+	// used for LWM2M 1.0 clients that have no "Q" parameter
+	BindingQueue *bool
+	SmsNumber    *string
 }
 
 func NewUpdateRequest(queries []string) (req *UpdateRequest, err error) {
@@ -117,6 +137,11 @@ func NewUpdateRequest(queries []string) (req *UpdateRequest, err error) {
 		SmsNumber:   nil,
 	}
 	for _, val := range queries {
+		if val == "Q" {
+			queue := true
+			req.Queue = &queue
+			continue
+		}
 		sps := strings.Split(val, "=")
 		if len(sps) != 2 {
 			continue
@@ -132,12 +157,21 @@ func NewUpdateRequest(queries []string) (req *UpdateRequest, err error) {
 		case "b":
 			binding := NewBinding(sps[1])
 			req.BindingMode = &binding
+			_, queue := ParseBinding(sps[1])
+			req.BindingQueue = &queue
 		default:
 		}
 	}
 	return
 }
 
+// DeviceConnGetter is implemented by managers that can find the device
+// registered on a connection, the default manager implement it
+type DeviceConnGetter interface {
+	// GetDeviceByConn return the device registered on conn
+	GetDeviceByConn(conn mux.Conn) (*Device, error)
+}
+
 type Manager interface {
 	Register(req *RegisterRequest, links []*encoding.CoreLink, conn mux.Conn) (*Device, error)
 	// PostRegister call by transport when register response send
@@ -158,6 +192,9 @@ type manager struct {
 
 	deviceStateChangeCb OnDeviceStateChangeFunc
 	logger              logging.LeveledLogger
+	awakeWindow         time.Duration
+	queueSize           int
+	overflow            OverflowPolicy
 
 	eventChan chan DeviceEvent
 }
@@ -187,7 +224,7 @@ func (d *manager) Update(id string, req *UpdateRequest, links []*encoding.CoreLi
 		return ErrDeviceNotFound
 	}
 	if dev.conn.RemoteAddr().String() != conn.RemoteAddr().String() {
-		dev.conn = conn
+		dev.setConn(conn)
 	}
 	if req.Lifetime != nil {
 		dev.Lifetime = *req.Lifetime
@@ -199,6 +236,17 @@ func (d *manager) Update(id string, req *UpdateRequest, links []*encoding.CoreLi
 	if req.SmsNumber != nil {
 		dev.Sms = req.SmsNumber
 	}
+	queue := req.Queue
+	if queue == nil && dev.Version == "1.0" {
+		queue = req.BindingQueue
+	}
+	if queue != nil {
+		dev.queueLock.Lock()
+		dev.Queue = *queue
+		dev.queueLock.Unlock()
+	}
+	// queued requests are sent once the update is received
+	dev.MarkAwake()
 	if links != nil && len(links) > 0 {
 		dev.ParseCoreLinks(links)
 	}
@@ -260,6 +308,17 @@ func (d *manager) GetDeviceByEP(ep string) (*Device, error) {
 	return d.getDeviceByEP(ep)
 }
 
+func (d *manager) GetDeviceByConn(conn mux.Conn) (*Device, error) {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+	for _, dev := range d.devices {
+		if dev.usesConn(conn) {
+			return dev, nil
+		}
+	}
+	return nil, ErrDeviceNotFound
+}
+
 func (d *manager) OnDeviceStateChange(f OnDeviceStateChangeFunc) {
 	d.lock.Lock()
 	defer d.lock.Unlock()
@@ -269,7 +328,12 @@ func (d *manager) OnDeviceStateChange(f OnDeviceStateChangeFunc) {
 func (d *manager) Register(req *RegisterRequest, links []*encoding.CoreLink, conn mux.Conn) (*Device, error) {
 	d.lock.Lock()
 	defer d.lock.Unlock()
+	// observations are kept per endpoint and restored to the new device
+	var intents map[any]Observation
+	var old *Device
 	if dev, err := d.getDeviceByEP(req.Ep); err == nil {
+		intents = dev.observationIntents()
+		old = dev
 		_ = d.deregister(dev.Id)
 	}
 	conn.SetContextValue(lifetimeCtxKey, time.Second*time.Duration(req.Lifetime))
@@ -281,12 +345,37 @@ func (d *manager) Register(req *RegisterRequest, links []*encoding.CoreLink, con
 		Endpoint:    req.Ep,
 		Version:     req.Version,
 		BindingMode: req.BindingMode,
-		conn:        conn,
 		Lifetime:    req.Lifetime,
 		Sms:         req.SmsNumber,
+		Queue:       req.Queue,
+		awakeWindow: d.awakeWindow,
 		objs:        make(map[uint16]*node.Object),
+		states:      make(map[node.Path]ResourceState),
 		Manager:     d,
-		obsChan:     make(chan observationEvent, 10),
+		notifiers:   make(map[any]*notifier),
+		queueSize:   d.queueSize,
+		overflow:    d.overflow,
+	}
+	if old != nil {
+		dev.moveSubscriptions(old)
+	} else {
+		dev.subs = newSubscriptions(dev)
+	}
+	dev.setConn(conn)
+	dev.MarkAwake()
+	if len(intents) > 0 {
+		dev.keepObservations(intents)
+		dev.onRestore = func(restored []RestoredObservation) {
+			d.lock.RLock()
+			defer d.lock.RUnlock()
+			if d.deviceStateChangeCb != nil {
+				d.eventChan <- DeviceEvent{
+					EventType: DeviceObservationsRestored,
+					Device:    dev,
+					Restored:  restored,
+				}
+			}
+		}
 	}
 	dev.SetMediaTypes(DefaultMediaType, DefaultMediaType)
 	if links != nil && len(links) > 0 {
@@ -330,14 +419,20 @@ func (d *manager) run() {
 }
 
 type ManagerConfig struct {
-	logger logging.LeveledLogger
-	ctx    context.Context
+	logger      logging.LeveledLogger
+	ctx         context.Context
+	awakeWindow time.Duration
+	queueSize   int
+	overflow    OverflowPolicy
 }
 
 func newManagerConfig() *ManagerConfig {
 	return &ManagerConfig{
-		logger: nil,
-		ctx:    context.Background(),
+		logger:      nil,
+		ctx:         context.Background(),
+		awakeWindow: DefaultAwakeWindow,
+		queueSize:   DefaultNotificationQueueSize,
+		overflow:    OverflowDropOldest,
 	}
 }
 
@@ -355,6 +450,23 @@ func WithContext(ctx context.Context) ManagerOption {
 	}
 }
 
+// WithAwakeWindow set the awake window of queue mode devices, the time a
+// device stay online after it send a message
+func WithAwakeWindow(w time.Duration) ManagerOption {
+	return func(o *ManagerConfig) {
+		o.awakeWindow = w
+	}
+}
+
+// WithNotificationQueue set the notification queue size of each
+// observation and what to do when it is full
+func WithNotificationQueue(size int, policy OverflowPolicy) ManagerOption {
+	return func(o *ManagerConfig) {
+		o.queueSize = size
+		o.overflow = policy
+	}
+}
+
 func DefaultManager(opts ...ManagerOption) Manager {
 	cfg := newManagerConfig()
 	for _, opt := range opts {
@@ -365,11 +477,14 @@ func DefaultManager(opts ...ManagerOption) Manager {
 		cfg.logger = lf.NewLogger("device_manager")
 	}
 	dm := &manager{
-		ctx:       cfg.ctx,
-		devices:   make(map[string]*Device),
-		epToID:    make(map[string]string),
-		logger:    cfg.logger,
-		eventChan: make(chan DeviceEvent, 1000),
+		ctx:         cfg.ctx,
+		devices:     make(map[string]*Device),
+		epToID:      make(map[string]string),
+		logger:      cfg.logger,
+		awakeWindow: cfg.awakeWindow,
+		queueSize:   cfg.queueSize,
+		overflow:    cfg.overflow,
+		eventChan:   make(chan DeviceEvent, 1000),
 	}
 	go dm.run()
 	return dm
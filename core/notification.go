@@ -0,0 +1,50 @@
+package core
+
+import (
+	"github.com/plgd-dev/go-coap/v3/message"
+	"github.com/plgd-dev/go-coap/v3/message/pool"
+	"github.com/yplam/lwm2m/node"
+	"net"
+	"time"
+)
+
+// Notification is the metadata of a notification received from the device,
+// it is shared by the callbacks of the notification and must not be
+// modified
+type Notification struct {
+	// Path is the path of the observation, it may be an ancestor of the
+	// path of a subscription
+	Path node.Path
+	// Paths are the paths of a composite observation
+	Paths []node.Path
+	// Sequence is the CoAP Observe option value, HasSequence is false if
+	// the notification does not have one
+	Sequence    uint32
+	HasSequence bool
+	// Time is when the notification was received
+	Time          time.Time
+	ContentFormat message.MediaType
+	RemoteAddr    net.Addr
+	Confirmable   bool
+	// Payload is the raw payload of the notification
+	Payload []byte
+}
+
+func (d *Device) newNotification(msg *pool.Message) *Notification {
+	n := &Notification{
+		Time:        time.Now(),
+		Confirmable: msg.Type() == message.Confirmable,
+		RemoteAddr:  d.conn.RemoteAddr(),
+	}
+	if seq, err := msg.Observe(); err == nil {
+		n.Sequence = seq
+		n.HasSequence = true
+	}
+	if cf, err := msg.ContentFormat(); err == nil {
+		n.ContentFormat = cf
+	}
+	if payload, err := msg.ReadBody(); err == nil {
+		n.Payload = payload
+	}
+	return n
+}
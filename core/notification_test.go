@@ -0,0 +1,94 @@
+package core
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/plgd-dev/go-coap/v3/message"
+	"github.com/plgd-dev/go-coap/v3/message/codes"
+	"github.com/plgd-dev/go-coap/v3/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/yplam/lwm2m/node"
+)
+
+func TestNotificationMeta(t *testing.T) {
+	payload := `[{"bn":"/3/0/9","v":95}]`
+	d := newTestDevice(t, func(w mux.ResponseWriter, r *mux.Message) {
+		_ = w.SetResponse(codes.Content, message.AppSenmlJSON, bytes.NewReader([]byte(payload)))
+		if newTestRequest(r).observe == 0 {
+			w.Message().SetObserve(5)
+		}
+	})
+	d.SetMediaTypes(message.AppSenmlJSON, message.AppSenmlJSON)
+	go d.run()
+	p := node.NewResourcePath(3, 0, 9)
+
+	metas := make(chan *Notification, 2)
+	err := d.ObserveWithMeta(p, func(d *Device, p node.Path, notify []node.Node, meta *Notification) {
+		metas <- meta
+	})
+	assert.Nil(t, err)
+	s, err := d.SubscribeWithMeta(testContext(t), node.NewResourcePath(3, 0, 9),
+		func(d *Device, p node.Path, notify []node.Node, meta *Notification) {
+			metas <- meta
+		})
+	assert.Nil(t, err)
+	defer s.Close()
+
+	// the observation and the subscription get the same metadata
+	var n *Notification
+	for i := 0; i < 2; i++ {
+		select {
+		case n = <-metas:
+		case <-time.After(5 * time.Second):
+			t.Fatal("no notification")
+		}
+	}
+	assert.NotNil(t, n)
+	assert.Equal(t, p, n.Path)
+	assert.Equal(t, uint32(5), n.Sequence)
+	assert.True(t, n.HasSequence)
+	assert.Equal(t, message.AppSenmlJSON, n.ContentFormat)
+	assert.Equal(t, payload, string(n.Payload))
+	assert.Equal(t, d.conn.RemoteAddr(), n.RemoteAddr)
+	assert.False(t, n.Confirmable)
+	assert.WithinDuration(t, time.Now(), n.Time, 5*time.Second)
+}
+
+func TestObserveHistory(t *testing.T) {
+	d := newTestDevice(t, func(w mux.ResponseWriter, r *mux.Message) {
+		_ = w.SetResponse(codes.Content, message.AppSenmlJSON, bytes.NewReader([]byte(
+			`[{"bn":"/3303/0/5700","bt":1700000000,"v":21.5,"t":-60},{"v":22.5}]`)))
+		if newTestRequest(r).observe == 0 {
+			w.Message().SetObserve(2)
+		}
+	})
+	d.SetMediaTypes(message.AppSenmlJSON, message.AppSenmlJSON)
+	p := node.NewResourcePath(3303, 0, 5700)
+	histories := make(chan []*node.ResourceInstance, 1)
+	err := d.ObserveSync(p, wrapObserveResourceFunc(func(d *Device, p node.Path, notify *node.Resource) {
+		histories <- notify.History(0)
+	}))
+	assert.Nil(t, err)
+
+	var history []*node.ResourceInstance
+	select {
+	case history = <-histories:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no notification")
+	}
+	assert.Equal(t, 2, len(history))
+	assert.Equal(t, 21.5, history[0].Value())
+	ts, ok := history[0].Timestamp()
+	assert.True(t, ok)
+	assert.Equal(t, time.Unix(1699999940, 0), ts)
+	assert.Equal(t, 22.5, history[1].Value())
+
+	// the state keep the latest value
+	s, ok := d.State(p)
+	assert.True(t, ok)
+	ins, err := s.Resource.GetInstance(0)
+	assert.Nil(t, err)
+	assert.Equal(t, 22.5, ins.Value())
+}
@@ -1,18 +1,138 @@
 package core
 
 import (
+	"context"
 	"fmt"
 	"github.com/plgd-dev/go-coap/v3/mux"
 	"github.com/yplam/lwm2m/node"
+	"strings"
+	"time"
 )
 
+// ObserveFunc receive the nodes of one notification of p, the values stored
+// by the client while offline are available with node.GetHistory
 type ObserveFunc func(d *Device, p node.Path, notify []node.Node)
+
+// ObserveFuncWithMeta is an ObserveFunc that also receive the metadata of
+// the notification
+type ObserveFuncWithMeta func(d *Device, p node.Path, notify []node.Node, meta *Notification)
 type ObserveObjectFunc func(d *Device, p node.Path, notify *node.Object)
 type ObserveResourceFunc func(d *Device, p node.Path, notify *node.Resource)
 
+// ObserveCompositeFunc receive the resources of one composite notification,
+// keyed by their path
+type ObserveCompositeFunc func(d *Device, notify map[node.Path]*node.Resource)
+
+// ObserveCompositeFuncWithMeta is an ObserveCompositeFunc that also receive
+// the metadata of the notification
+type ObserveCompositeFuncWithMeta func(d *Device, notify map[node.Path]*node.Resource, meta *Notification)
+
 type Observation struct {
 	o  mux.Observation
-	cb ObserveFunc
+	cb ObserveFuncWithMeta
+	// paths of a composite observation, nil otherwise
+	paths []node.Path
+	// attrs are written again when the observation is restored
+	attrs *node.Attributes
+	// pending is set while the observe request is in flight
+	pending bool
+}
+
+// RestoredObservation is the result of re-issuing an observation kept from
+// a previous registration of the device, Paths is set for a composite
+// observation, Path otherwise
+type RestoredObservation struct {
+	Path  node.Path
+	Paths []node.Path
+	Err   error
+}
+
+// observationIntents return the observations of the device without their
+// CoAP observation, they can be restored to a new registration
+func (d *Device) observationIntents() map[any]Observation {
+	intents := make(map[any]Observation)
+	d.observations.Range(func(key, value any) bool {
+		v := value.(Observation)
+		v.o = nil
+		v.pending = false
+		intents[key] = v
+		return true
+	})
+	return intents
+}
+
+// keepObservations add the observations of a previous registration, they
+// are re-issued when the device start
+func (d *Device) keepObservations(intents map[any]Observation) {
+	for key, v := range intents {
+		d.observations.Store(key, v)
+		d.restoring = append(d.restoring, key)
+	}
+}
+
+// restoreObservations re-issue the observations of a previous registration
+// and their attributes. Observations that fail are kept and retried like
+// other observations
+func (d *Device) restoreObservations() {
+	keys := d.restoring
+	d.restoring = nil
+	if len(keys) == 0 {
+		return
+	}
+	restored := make([]RestoredObservation, 0, len(keys))
+	for _, key := range keys {
+		val, ok := d.observations.Load(key)
+		if !ok {
+			continue
+		}
+		v := val.(Observation)
+		r := RestoredObservation{Paths: v.paths}
+		if p, ok := key.(node.Path); ok {
+			r.Path = p
+		}
+		if v.o != nil && !v.o.Canceled() {
+			restored = append(restored, r)
+			continue
+		}
+		ctx, cancel := context.WithTimeout(d.ctx, time.Duration(d.Lifetime)*time.Second)
+		if v.attrs != nil && len(v.paths) == 0 {
+			r.Err = d.WriteAttributes(ctx, r.Path, v.attrs)
+		}
+		if r.Err == nil {
+			r.Err = d.establishObservation(ctx, key)
+		}
+		cancel()
+		restored = append(restored, r)
+	}
+	if d.onRestore != nil {
+		d.onRestore(restored)
+	}
+}
+
+// compositeKey identify a composite observation in Device.observations,
+// it is the sorted path list
+type compositeKey string
+
+func newCompositeKey(paths []node.Path) (compositeKey, []node.Path) {
+	sorted := make([]node.Path, len(paths))
+	copy(sorted, paths)
+	node.SortPaths(sorted)
+	s := make([]string, len(sorted))
+	for i, p := range sorted {
+		s[i] = p.String()
+	}
+	return compositeKey(strings.Join(s, ",")), sorted
+}
+
+// withMeta wrap f as an ObserveFuncWithMeta that ignore the metadata, nil
+// is kept as nil
+func withMeta(f ObserveFunc) ObserveFuncWithMeta {
+	if f == nil {
+		return nil
+	}
+	return func(d *Device, p node.Path, notify []node.Node, meta *Notification) {
+		f(d, p, notify)
+	}
 }
 
 func wrapObserveResourceFunc(f ObserveResourceFunc) ObserveFunc {
@@ -32,3 +152,11 @@ func wrapObserveObjectFunc(f ObserveObjectFunc) ObserveFunc {
 		}
 	}
 }
+
+func wrapObserveCompositeFunc(f ObserveCompositeFuncWithMeta) ObserveFuncWithMeta {
+	return func(d *Device, p node.Path, notify []node.Node, meta *Notification) {
+		if data, err := node.GetAllResources(notify, p); err == nil {
+			f(d, data, meta)
+		}
+	}
+}
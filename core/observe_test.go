@@ -0,0 +1,83 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/plgd-dev/go-coap/v3/message"
+	"github.com/plgd-dev/go-coap/v3/message/codes"
+	"github.com/plgd-dev/go-coap/v3/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/yplam/lwm2m/node"
+)
+
+func TestRestoreObservations(t *testing.T) {
+	var lock sync.Mutex
+	attrWrites := make([]string, 0)
+	d := newTestDevice(t, func(w mux.ResponseWriter, r *mux.Message) {
+		req := newTestRequest(r)
+		switch {
+		case req.code == codes.PUT:
+			lock.Lock()
+			attrWrites = append(attrWrites, req.path)
+			lock.Unlock()
+			_ = w.SetResponse(codes.Changed, message.TextPlain, nil)
+		case req.path == "/3/0/9":
+			_ = w.SetResponse(codes.Content, message.AppSenmlJSON, bytes.NewReader([]byte(
+				`[{"bn":"/3/0/9","v":95}]`)))
+			if req.observe == 0 {
+				w.Message().SetObserve(2)
+			}
+		default:
+			_ = w.SetResponse(codes.NotFound, message.TextPlain, nil)
+		}
+	})
+	conn := d.conn.(*queueConn).Conn
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m := DefaultManager(WithContext(ctx))
+	events := make(chan DeviceEvent, 1)
+	m.OnDeviceStateChange(func(e DeviceEvent, m Manager) {
+		if e.EventType == DeviceObservationsRestored {
+			events <- e
+		}
+	})
+	req := &RegisterRequest{Ep: "test", Lifetime: 60, Version: "1.1"}
+
+	dev, err := m.Register(req, nil, conn)
+	assert.Nil(t, err)
+	pmin := 10
+	attrs := &node.Attributes{Pmin: &pmin}
+	p := node.NewResourcePath(3, 0, 9)
+	cb := func(d *Device, p node.Path, notify []node.Node) {}
+	assert.Nil(t, dev.ObserveWithAttributes(testContext(t), p, attrs, cb))
+	assert.Nil(t, dev.Observe(node.NewResourcePath(3303, 0, 5700), cb))
+
+	dev2, err := m.Register(req, nil, conn)
+	assert.Nil(t, err)
+	assert.NotEqual(t, dev.Id, dev2.Id)
+	var e DeviceEvent
+	select {
+	case e = <-events:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no restore event")
+	}
+	assert.Equal(t, dev2, e.Device)
+	assert.Equal(t, 2, len(e.Restored))
+	for _, r := range e.Restored {
+		if r.Path == p {
+			assert.Nil(t, r.Err)
+		} else {
+			assert.Equal(t, node.NewResourcePath(3303, 0, 5700), r.Path)
+			assert.NotNil(t, r.Err)
+		}
+	}
+	lock.Lock()
+	assert.Equal(t, []string{"/3/0/9", "/3/0/9"}, attrWrites)
+	lock.Unlock()
+	_, ok := dev2.observations.Load(p)
+	assert.True(t, ok)
+}
@@ -0,0 +1,224 @@
+package core
+
+import (
+	"context"
+	"github.com/plgd-dev/go-coap/v3/message"
+	"github.com/plgd-dev/go-coap/v3/message/pool"
+	"github.com/plgd-dev/go-coap/v3/mux"
+	"io"
+	"time"
+)
+
+// DefaultAwakeWindow is the time a queue mode client stay online after it
+// send a message, it is the CoAP MAX_TRANSMIT_WAIT
+const DefaultAwakeWindow = 93 * time.Second
+
+// queueConn hold the requests to a queue mode device until the device is
+// awake, requests that are answered extend the awake window
+type queueConn struct {
+	mux.Conn
+	d *Device
+}
+
+// usesConn return whether conn is the connection of the device
+func (d *Device) usesConn(conn mux.Conn) bool {
+	if qc, ok := d.conn.(*queueConn); ok {
+		return qc.Conn == conn
+	}
+	return d.conn == conn
+}
+
+func (c *queueConn) Get(ctx context.Context, path string, opts ...message.Option) (*pool.Message, error) {
+	done, err := c.d.waitAwake(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	return c.awake(c.Conn.Get(ctx, path, opts...))
+}
+
+func (c *queueConn) Delete(ctx context.Context, path string, opts ...message.Option) (*pool.Message, error) {
+	done, err := c.d.waitAwake(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	return c.awake(c.Conn.Delete(ctx, path, opts...))
+}
+
+func (c *queueConn) Post(ctx context.Context, path string, contentFormat message.MediaType, payload io.ReadSeeker, opts ...message.Option) (*pool.Message, error) {
+	done, err := c.d.waitAwake(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	return c.awake(c.Conn.Post(ctx, path, contentFormat, payload, opts...))
+}
+
+func (c *queueConn) Put(ctx context.Context, path string, contentFormat message.MediaType, payload io.ReadSeeker, opts ...message.Option) (*pool.Message, error) {
+	done, err := c.d.waitAwake(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	return c.awake(c.Conn.Put(ctx, path, contentFormat, payload, opts...))
+}
+
+func (c *queueConn) Do(req *pool.Message) (*pool.Message, error) {
+	done, err := c.d.waitAwake(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	return c.awake(c.Conn.Do(req))
+}
+
+func (c *queueConn) Observe(ctx context.Context, path string, observeFunc func(notification *pool.Message), opts ...message.Option) (mux.Observation, error) {
+	done, err := c.d.waitAwake(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	return c.Conn.Observe(ctx, path, observeFunc, opts...)
+}
+
+func (c *queueConn) DoObserve(req *pool.Message, observeFunc func(req *pool.Message)) (mux.Observation, error) {
+	done, err := c.d.waitAwake(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	return c.Conn.DoObserve(req, observeFunc)
+}
+
+func (c *queueConn) awake(resp *pool.Message, err error) (*pool.Message, error) {
+	if err == nil {
+		c.d.MarkAwake()
+	}
+	return resp, err
+}
+
+// setConn set the connection of the device, requests are held while the
+// device is in queue mode and sleeping
+func (d *Device) setConn(conn mux.Conn) {
+	if qc, ok := conn.(*queueConn); ok {
+		conn = qc.Conn
+	}
+	d.conn = &queueConn{Conn: conn, d: d}
+}
+
+// SetAwakeWindow set the time the device stay online after it send a
+// message, DefaultAwakeWindow is used if not set
+func (d *Device) SetAwakeWindow(w time.Duration) {
+	d.queueLock.Lock()
+	defer d.queueLock.Unlock()
+	d.awakeWindow = w
+}
+
+// AwakeUntil return the end of the current awake window of the device
+func (d *Device) AwakeUntil() time.Time {
+	d.queueLock.Lock()
+	defer d.queueLock.Unlock()
+	return d.awakeUntil
+}
+
+// IsAwake return true if the device can be reached now, a device not in
+// queue mode is always awake
+func (d *Device) IsAwake() bool {
+	d.queueLock.Lock()
+	defer d.queueLock.Unlock()
+	return !d.Queue || d.clock().Before(d.awakeUntil)
+}
+
+// clock return the current time, d.queueLock must be held
+func (d *Device) clock() time.Time {
+	if d.now != nil {
+		return d.now()
+	}
+	return time.Now()
+}
+
+// MarkAwake start a new awake window, it is called when the device send a
+// message like an Update or a notification. Requests held for a queue mode
+// device are released one by one in the order they were made, the next one
+// is sent when the previous one is answered
+func (d *Device) MarkAwake() {
+	d.queueLock.Lock()
+	defer d.queueLock.Unlock()
+	w := d.awakeWindow
+	if w == 0 {
+		w = DefaultAwakeWindow
+	}
+	d.awakeUntil = d.clock().Add(w)
+	d.releaseNext()
+}
+
+// releaseNext release the first held request if no released request is in
+// flight, d.queueLock must be held
+func (d *Device) releaseNext() {
+	if d.releasing || len(d.pending) == 0 {
+		return
+	}
+	close(d.pending[0])
+	d.pending = d.pending[1:]
+	d.releasing = true
+}
+
+// releaseDone end the turn of a released request, the next held request is
+// released if the device is still awake
+func (d *Device) releaseDone() {
+	d.queueLock.Lock()
+	defer d.queueLock.Unlock()
+	d.releasing = false
+	if !d.Queue || d.clock().Before(d.awakeUntil) {
+		d.releaseNext()
+	}
+}
+
+// hold return a channel that is closed when the request may be sent, it
+// is nil if the request can be sent now. Held requests are released in the
+// order hold was called
+func (d *Device) hold() chan struct{} {
+	d.queueLock.Lock()
+	defer d.queueLock.Unlock()
+	if !d.Queue || (len(d.pending) == 0 && !d.releasing && d.clock().Before(d.awakeUntil)) {
+		return nil
+	}
+	ch := make(chan struct{})
+	d.pending = append(d.pending, ch)
+	return ch
+}
+
+// waitAwake block until the device is awake or ctx is done, done must be
+// called once the request is answered
+func (d *Device) waitAwake(ctx context.Context) (done func(), err error) {
+	ch := d.hold()
+	if ch == nil {
+		return func() {}, nil
+	}
+	return d.waitRelease(ctx, ch)
+}
+
+// waitRelease block until ch, returned by hold, is released or ctx is done
+func (d *Device) waitRelease(ctx context.Context, ch chan struct{}) (done func(), err error) {
+	select {
+	case <-ch:
+		return d.releaseDone, nil
+	case <-ctx.Done():
+		d.queueLock.Lock()
+		held := false
+		for i, c := range d.pending {
+			if c == ch {
+				d.pending = append(d.pending[:i], d.pending[i+1:]...)
+				held = true
+				break
+			}
+		}
+		d.queueLock.Unlock()
+		if !held {
+			// released at the same time, give the turn to the next one
+			d.releaseDone()
+		}
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,208 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/plgd-dev/go-coap/v3/message"
+	"github.com/plgd-dev/go-coap/v3/message/codes"
+	"github.com/plgd-dev/go-coap/v3/mux"
+	"github.com/plgd-dev/go-coap/v3/udp"
+	"github.com/stretchr/testify/assert"
+	"github.com/yplam/lwm2m/node"
+)
+
+func TestParseBinding(t *testing.T) {
+	b, q := ParseBinding("UQ")
+	assert.Equal(t, "U", b)
+	assert.True(t, q)
+	b, q = ParseBinding("U")
+	assert.Equal(t, "U", b)
+	assert.False(t, q)
+	assert.Equal(t, UdpBinding, NewBinding("UQ"))
+	assert.Equal(t, SmsBinding, NewBinding("SQ"))
+
+	req, err := NewRegisterRequest([]string{"ep=test", "lwm2m=1.0", "b=UQ"})
+	assert.Nil(t, err)
+	assert.True(t, req.Queue)
+	assert.Equal(t, UdpBinding, req.BindingMode)
+	req, err = NewRegisterRequest([]string{"ep=test", "lwm2m=1.1", "Q"})
+	assert.Nil(t, err)
+	assert.True(t, req.Queue)
+	req, err = NewRegisterRequest([]string{"ep=test", "lwm2m=1.1"})
+	assert.Nil(t, err)
+	assert.False(t, req.Queue)
+	// the binding is a queue mode signal of LWM2M 1.0 clients only
+	ureq, err := NewUpdateRequest([]string{"b=U"})
+	assert.Nil(t, err)
+	assert.Nil(t, ureq.Queue)
+	if assert.NotNil(t, ureq.BindingQueue) {
+		assert.False(t, *ureq.BindingQueue)
+	}
+	ureq, err = NewUpdateRequest([]string{"lt=60", "Q"})
+	assert.Nil(t, err)
+	assert.Nil(t, ureq.BindingQueue)
+	if assert.NotNil(t, ureq.Queue) {
+		assert.True(t, *ureq.Queue)
+	}
+}
+
+// testClock is a clock that only move when told to
+type testClock struct {
+	lock sync.Mutex
+	t    time.Time
+}
+
+func newTestClock() *testClock {
+	return &testClock{t: time.Unix(0, 0)}
+}
+
+func (c *testClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.t
+}
+
+func (c *testClock) Add(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.t = c.t.Add(d)
+}
+
+// released return whether each of chs is closed
+func released(chs []chan struct{}) []bool {
+	r := make([]bool, len(chs))
+	for i, ch := range chs {
+		select {
+		case <-ch:
+			r[i] = true
+		default:
+		}
+	}
+	return r
+}
+
+func TestQueueMode(t *testing.T) {
+	reqs := make(chan testRequest, 2)
+	d := newTestDevice(t, func(w mux.ResponseWriter, r *mux.Message) {
+		reqs <- newTestRequest(r)
+		_ = w.SetResponse(codes.Content, message.TextPlain, bytes.NewReader([]byte("Open Mobile Alliance")))
+	})
+	clock := newTestClock()
+	d.now = clock.Now
+	d.Queue = true
+	d.SetAwakeWindow(time.Minute)
+	assert.False(t, d.IsAwake())
+
+	// not sent until the device is awake, reported through ctx
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := d.ReadResource(ctx, node.NewResourcePath(3, 0, 0))
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 0, len(d.pending))
+
+	type result struct {
+		res *node.Resource
+		err error
+	}
+	results := make(chan result, 1)
+	go func() {
+		res, err := d.ReadResource(testContext(t), node.NewResourcePath(3, 0, 0))
+		results <- result{res, err}
+	}()
+	assert.Eventually(t, func() bool {
+		d.queueLock.Lock()
+		defer d.queueLock.Unlock()
+		return len(d.pending) == 1
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, 0, len(reqs))
+	d.MarkAwake()
+	assert.True(t, d.IsAwake())
+	r := <-results
+	assert.Nil(t, r.err)
+	assert.Equal(t, "Open Mobile Alliance", r.res.Data().StringVal())
+	assert.Equal(t, "/3/0/0", (<-reqs).path)
+
+	// sent directly during the awake window
+	_, err = d.ReadResource(testContext(t), node.NewResourcePath(3, 0, 0))
+	assert.Nil(t, err)
+	<-reqs
+	assert.Equal(t, clock.Now().Add(time.Minute), d.AwakeUntil())
+	clock.Add(time.Minute)
+	assert.False(t, d.IsAwake())
+}
+
+func TestQueueModeOrder(t *testing.T) {
+	clock := newTestClock()
+	d := &Device{Queue: true, now: clock.Now}
+	d.SetAwakeWindow(time.Minute)
+	held := []chan struct{}{d.hold(), d.hold(), d.hold()}
+	assert.Equal(t, []bool{false, false, false}, released(held))
+
+	// one request is released at a time, in the order they were held
+	d.MarkAwake()
+	assert.Equal(t, []bool{true, false, false}, released(held))
+	// held while previous requests are not answered
+	held = append(held, d.hold())
+	assert.NotNil(t, held[3])
+	d.MarkAwake()
+	assert.Equal(t, []bool{true, false, false, false}, released(held))
+	d.releaseDone()
+	assert.Equal(t, []bool{true, true, false, false}, released(held))
+
+	// a canceled request give its turn to the next one
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := d.waitRelease(ctx, held[2])
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// not released once the awake window is over
+	clock.Add(time.Minute)
+	d.releaseDone()
+	assert.Equal(t, []bool{true, true, false, false}, released(held))
+	d.MarkAwake()
+	assert.Equal(t, []bool{true, true, false, true}, released(held))
+	d.releaseDone()
+	assert.Nil(t, d.hold())
+}
+
+func TestUpdateQueue(t *testing.T) {
+	d := newTestDevice(t, func(w mux.ResponseWriter, r *mux.Message) {})
+	conn := d.conn.(*queueConn).Conn
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m := DefaultManager(WithContext(ctx))
+
+	// the binding does not change the queue mode of LWM2M 1.1 clients
+	req, err := NewRegisterRequest([]string{"ep=test11", "lwm2m=1.1", "b=U", "Q"})
+	assert.Nil(t, err)
+	dev, err := m.Register(req, nil, conn)
+	assert.Nil(t, err)
+	ureq, err := NewUpdateRequest([]string{"b=U"})
+	assert.Nil(t, err)
+	assert.Nil(t, m.Update(dev.Id, ureq, nil, conn))
+	assert.True(t, dev.Queue)
+
+	req, err = NewRegisterRequest([]string{"ep=test10", "lwm2m=1.0", "b=UQ"})
+	assert.Nil(t, err)
+	dev, err = m.Register(req, nil, conn)
+	assert.Nil(t, err)
+	assert.True(t, dev.Queue)
+	assert.Nil(t, m.Update(dev.Id, ureq, nil, conn))
+	assert.False(t, dev.Queue)
+}
+
+func TestUsesConn(t *testing.T) {
+	d := newTestDevice(t, func(w mux.ResponseWriter, r *mux.Message) {})
+	qc, ok := d.conn.(*queueConn)
+	assert.True(t, ok)
+	assert.True(t, d.usesConn(qc.Conn))
+	assert.False(t, d.usesConn(qc))
+	other, err := udp.Dial(qc.RemoteAddr().String())
+	assert.Nil(t, err)
+	defer other.Close()
+	assert.False(t, d.usesConn(other))
+}
@@ -0,0 +1,143 @@
+package core
+
+import (
+	"context"
+	"github.com/yplam/lwm2m/node"
+	"sort"
+	"time"
+)
+
+// StateSource tell how the last known value of a resource was received
+type StateSource int
+
+const (
+	StateRead StateSource = iota
+	StateNotify
+	StateSend
+)
+
+func (s StateSource) String() string {
+	switch s {
+	case StateRead:
+		return "Read"
+	case StateNotify:
+		return "Notify"
+	case StateSend:
+		return "Send"
+	default:
+		return "Unknow"
+	}
+}
+
+// ResourceState is the last known value of a resource of the device
+type ResourceState struct {
+	Resource *node.Resource
+	// Time is when the value was received
+	Time   time.Time
+	Source StateSource
+}
+
+// UpdateState store the resources of nodes as the last known state of the
+// device, nodes are decoded from base. A resource instance base update the
+// instance in the state of its resource, if the resource has one
+func (d *Device) UpdateState(base node.Path, nodes []node.Node, source StateSource) {
+	instance := base.IsResourceInstance()
+	if instance {
+		base, _ = base.Parent()
+	}
+	resources, err := node.GetAllResources(nodes, base)
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	d.stateLock.Lock()
+	defer d.stateLock.Unlock()
+	for p, res := range resources {
+		if instance {
+			old, ok := d.states[p]
+			if !ok {
+				// the other instances are unknown
+				continue
+			}
+			if res = mergeInstances(p, old.Resource, res); res == nil {
+				continue
+			}
+		}
+		d.states[p] = ResourceState{
+			Resource: res,
+			Time:     now,
+			Source:   source,
+		}
+	}
+}
+
+// mergeInstances return a copy of the resource p with the instances of
+// update set, nil if it can not be created
+func mergeInstances(p node.Path, old, update *node.Resource) *node.Resource {
+	res, err := node.NewResource(p, true)
+	if err != nil {
+		return nil
+	}
+	for _, r := range []*node.Resource{old, update} {
+		for _, id := range r.InstanceIds() {
+			ins, _ := r.GetInstance(id)
+			_ = res.SetInstance(ins)
+		}
+	}
+	return res
+}
+
+// State return the last known state of the resource p
+func (d *Device) State(p node.Path) (ResourceState, bool) {
+	d.stateLock.RLock()
+	defer d.stateLock.RUnlock()
+	s, ok := d.states[p]
+	return s, ok
+}
+
+// States return the last known state of all resources that have been read
+// or notified, keyed by resource path
+func (d *Device) States() map[node.Path]ResourceState {
+	d.stateLock.RLock()
+	defer d.stateLock.RUnlock()
+	states := make(map[node.Path]ResourceState, len(d.states))
+	for p, s := range d.states {
+		states[p] = s
+	}
+	return states
+}
+
+// ReadResourceCached return the last known state of the resource p if it is
+// not older than maxAge, the resource is read from the device otherwise
+func (d *Device) ReadResourceCached(ctx context.Context, p node.Path, maxAge time.Duration) (*node.Resource, error) {
+	if s, ok := d.State(p); ok && time.Since(s.Time) <= maxAge {
+		return s.Resource, nil
+	}
+	return d.ReadResource(ctx, p)
+}
+
+// Snapshot read every object advertised by the device to refresh its
+// state, and return the states. The Security object is not readable by
+// the server and is skipped. Objects that can not be read are skipped,
+// the first error is returned with the states
+func (d *Device) Snapshot(ctx context.Context) (map[node.Path]ResourceState, error) {
+	d.objLock.RLock()
+	ids := make([]uint16, 0, len(d.objs))
+	for id := range d.objs {
+		if id != 0 {
+			ids = append(ids, id)
+		}
+	}
+	d.objLock.RUnlock()
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	var firstErr error
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			return d.States(), ctx.Err()
+		}
+		if _, err := d.Read(ctx, node.NewObjectPath(id)); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return d.States(), firstErr
+}
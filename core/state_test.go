@@ -0,0 +1,114 @@
+package core
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/plgd-dev/go-coap/v3/message"
+	"github.com/plgd-dev/go-coap/v3/message/codes"
+	"github.com/plgd-dev/go-coap/v3/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/yplam/lwm2m/encoding"
+	"github.com/yplam/lwm2m/node"
+)
+
+func TestState(t *testing.T) {
+	reqs := make(chan testRequest, 10)
+	d := newTestDevice(t, func(w mux.ResponseWriter, r *mux.Message) {
+		req := newTestRequest(r)
+		reqs <- req
+		switch req.path {
+		case "/3/0/9":
+			_ = w.SetResponse(codes.Content, message.AppSenmlJSON, bytes.NewReader([]byte(
+				`[{"bn":"/3/0/9","v":95}]`)))
+		case "/3":
+			_ = w.SetResponse(codes.Content, message.AppSenmlJSON, bytes.NewReader([]byte(
+				`[{"bn":"/3/0/","n":"0","vs":"Open Mobile Alliance"},{"n":"9","v":95}]`)))
+		case "/3303":
+			_ = w.SetResponse(codes.Content, message.AppSenmlJSON, bytes.NewReader([]byte(
+				`[{"bn":"/3303/0/","n":"5700","v":21.5}]`)))
+		default:
+			_ = w.SetResponse(codes.Unauthorized, message.TextPlain, nil)
+		}
+	})
+	d.SetMediaTypes(message.AppSenmlJSON, message.AppSenmlJSON)
+	links, err := encoding.CoreLinksFromString("</0/0>,</3/0>,</3303/0>,</3304>")
+	assert.Nil(t, err)
+	d.ParseCoreLinks(links)
+
+	_, ok := d.State(node.NewResourcePath(3, 0, 9))
+	assert.False(t, ok)
+	res, err := d.ReadResourceCached(testContext(t), node.NewResourcePath(3, 0, 9), time.Minute)
+	assert.Nil(t, err)
+	ins, err := res.GetInstance(0)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(95), ins.Value())
+	<-reqs
+	s, ok := d.State(node.NewResourcePath(3, 0, 9))
+	assert.True(t, ok)
+	assert.Equal(t, StateRead, s.Source)
+	// served from the state
+	res, err = d.ReadResourceCached(testContext(t), node.NewResourcePath(3, 0, 9), time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, s.Resource, res)
+	assert.Equal(t, 0, len(reqs))
+	_, err = d.ReadResourceCached(testContext(t), node.NewResourcePath(3, 0, 9), 0)
+	assert.Nil(t, err)
+	<-reqs
+
+	states, err := d.Snapshot(testContext(t))
+	assert.ErrorIs(t, err, ErrUnauthorized)
+	paths := make([]string, 0)
+	for len(reqs) > 0 {
+		paths = append(paths, (<-reqs).path)
+	}
+	assert.Equal(t, []string{"/3", "/3303", "/3304"}, paths)
+	assert.Equal(t, 3, len(states))
+	ins, err = states[node.NewResourcePath(3303, 0, 5700)].Resource.GetInstance(0)
+	assert.Nil(t, err)
+	assert.Equal(t, 21.5, ins.Value())
+
+	res, err = node.NewSingleResourceValue(node.NewResourcePath(3303, 0, 5700), 22.5)
+	assert.Nil(t, err)
+	d.UpdateState(node.NewRootPath(), []node.Node{res}, StateSend)
+	s, ok = d.State(node.NewResourcePath(3303, 0, 5700))
+	assert.True(t, ok)
+	assert.Equal(t, StateSend, s.Source)
+	assert.Equal(t, res, s.Resource)
+}
+
+func TestStateResourceInstance(t *testing.T) {
+	d := newTestDevice(t, func(w mux.ResponseWriter, r *mux.Message) {
+		switch newTestRequest(r).path {
+		case "/3/0/7":
+			_ = w.SetResponse(codes.Content, message.AppSenmlJSON, bytes.NewReader([]byte(
+				`[{"bn":"/3/0/7/","n":"0","v":3800},{"n":"1","v":5000}]`)))
+		case "/3/0/7/1":
+			_ = w.SetResponse(codes.Content, message.AppSenmlJSON, bytes.NewReader([]byte(
+				`[{"bn":"/3/0/7/1","v":4900}]`)))
+		}
+	})
+	d.SetMediaTypes(message.AppSenmlJSON, message.AppSenmlJSON)
+	p := node.NewResourcePath(3, 0, 7)
+
+	// no state to update yet
+	_, err := d.Read(testContext(t), node.NewResourceInstancePath(3, 0, 7, 1))
+	assert.Nil(t, err)
+	_, ok := d.State(p)
+	assert.False(t, ok)
+
+	_, err = d.Read(testContext(t), p)
+	assert.Nil(t, err)
+	_, err = d.Read(testContext(t), node.NewResourceInstancePath(3, 0, 7, 1))
+	assert.Nil(t, err)
+	s, ok := d.State(p)
+	assert.True(t, ok)
+	assert.Equal(t, []uint16{0, 1}, s.Resource.InstanceIds())
+	ins, err := s.Resource.GetInstance(0)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3800), ins.Value())
+	ins, err = s.Resource.GetInstance(1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(4900), ins.Value())
+}
@@ -0,0 +1,234 @@
+package core
+
+import (
+	"context"
+	"github.com/yplam/lwm2m/node"
+	"sync"
+)
+
+// Subscription is a callback registered with Device.Subscribe, it receive
+// the notifications of its path until it is closed
+type Subscription struct {
+	set  *subscriptions
+	path node.Path
+	cb   ObserveFuncWithMeta
+	once sync.Once
+}
+
+// subscriptions hold the subscriptions of an endpoint, they move to the new
+// device when the endpoint register again
+type subscriptions struct {
+	lock   sync.Mutex
+	d      *Device
+	byPath map[node.Path][]*Subscription
+}
+
+func newSubscriptions(d *Device) *subscriptions {
+	return &subscriptions{
+		d:      d,
+		byPath: make(map[node.Path][]*Subscription),
+	}
+}
+
+// Path return the path of the subscription
+func (s *Subscription) Path() node.Path {
+	return s.path
+}
+
+// Close remove the subscription, the observation serving it is canceled if
+// no other subscription use it
+func (s *Subscription) Close() error {
+	s.once.Do(func() {
+		s.set.lock.Lock()
+		defer s.set.lock.Unlock()
+		s.remove()
+		s.set.d.pruneObservations()
+	})
+	return nil
+}
+
+// remove delete s from its set, s.set.lock must be held
+func (s *Subscription) remove() {
+	subs := s.set.byPath[s.path]
+	for i, v := range subs {
+		if v == s {
+			subs = append(subs[:i], subs[i+1:]...)
+			break
+		}
+	}
+	if len(subs) == 0 {
+		delete(s.set.byPath, s.path)
+	} else {
+		s.set.byPath[s.path] = subs
+	}
+}
+
+// Subscribe call onMsg with the notifications of p. Subscriptions are
+// reference counted: the first one send the observe request with ctx, the
+// last one closed cancel it, and a subscription is served by the
+// observation of an ancestor path if there is one
+func (d *Device) Subscribe(ctx context.Context, p node.Path, onMsg ObserveFunc) (*Subscription, error) {
+	return d.SubscribeWithMeta(ctx, p, withMeta(onMsg))
+}
+
+// SubscribeWithMeta is Subscribe with a callback that receive the metadata
+// of each notification, its Path is the path of the observation serving the
+// subscription
+func (d *Device) SubscribeWithMeta(ctx context.Context, p node.Path, onMsg ObserveFuncWithMeta) (*Subscription, error) {
+	if p.IsRoot() {
+		return nil, node.ErrPathInvalidValue
+	}
+	set := d.subs
+	set.lock.Lock()
+	s := &Subscription{
+		set:  set,
+		path: p,
+		cb:   onMsg,
+	}
+	set.byPath[p] = append(set.byPath[p], s)
+	if key, ok := d.servingPath(p); ok && (d.isEstablished(key) || d.isPending(key)) {
+		d.pruneObservations()
+		set.lock.Unlock()
+		return s, nil
+	}
+	// the observation is stored before the request so that the first
+	// notification is dispatched
+	if _, ok := d.observations.Load(p); !ok {
+		d.observations.Store(p, Observation{})
+	}
+	set.lock.Unlock()
+
+	// the lock is not held during the request, it may wait for a queue
+	// mode device to wake up
+	err := d.establishObservation(ctx, p)
+	set.lock.Lock()
+	defer set.lock.Unlock()
+	if err != nil {
+		s.remove()
+		d.pruneObservations()
+		return nil, err
+	}
+	d.pruneObservations()
+	return s, nil
+}
+
+// ancestorPaths return the ancestors of p and p, from the object path
+func ancestorPaths(p node.Path) []node.Path {
+	paths := []node.Path{p}
+	for {
+		parent, err := p.Parent()
+		if err != nil || parent.IsRoot() {
+			break
+		}
+		paths = append([]node.Path{parent}, paths...)
+		p = parent
+	}
+	return paths
+}
+
+// isPending return true if the observe request of key is in flight
+func (d *Device) isPending(key node.Path) bool {
+	val, ok := d.observations.Load(key)
+	return ok && val.(Observation).pending
+}
+
+func (d *Device) isEstablished(key node.Path) bool {
+	val, ok := d.observations.Load(key)
+	if !ok {
+		return false
+	}
+	o := val.(Observation)
+	return o.o != nil && !o.o.Canceled()
+}
+
+// servingPath return the observation that notify subscribers of p, it is
+// the established or pending observation of the highest ancestor, or the
+// highest ancestor observation if none is
+func (d *Device) servingPath(p node.Path) (node.Path, bool) {
+	var highest *node.Path
+	for _, a := range ancestorPaths(p) {
+		if _, ok := d.observations.Load(a); !ok {
+			continue
+		}
+		if d.isEstablished(a) || d.isPending(a) {
+			return a, true
+		}
+		if highest == nil {
+			a := a
+			highest = &a
+		}
+	}
+	if highest != nil {
+		return *highest, true
+	}
+	return node.Path{}, false
+}
+
+// pruneObservations cancel the observations that were sent for
+// subscriptions and serve none of them anymore, d.subs.lock must be held
+func (d *Device) pruneObservations() {
+	used := make(map[node.Path]bool)
+	for p := range d.subs.byPath {
+		if key, ok := d.servingPath(p); ok {
+			used[key] = true
+		}
+	}
+	d.observations.Range(func(key, value any) bool {
+		p, ok := key.(node.Path)
+		if !ok || value.(Observation).cb != nil || used[p] {
+			return true
+		}
+		_ = d.cancelObservation(p)
+		return true
+	})
+}
+
+// hasSubscribers return true if the observation of key serve a
+// subscription
+func (d *Device) hasSubscribers(key node.Path) bool {
+	for p := range d.subs.byPath {
+		if sp, ok := d.servingPath(p); ok && sp == key {
+			return true
+		}
+	}
+	return false
+}
+
+// notifySubscribers call the subscriptions served by the observation of
+// key with the part of nodes they subscribed to
+func (d *Device) notifySubscribers(key any, nodes []node.Node, meta *Notification) {
+	kp, ok := key.(node.Path)
+	if !ok {
+		return
+	}
+	d.subs.lock.Lock()
+	subs := make([]*Subscription, 0)
+	for p, v := range d.subs.byPath {
+		if !p.IsChildOfOrEq(kp) {
+			continue
+		}
+		if sp, ok := d.servingPath(p); ok && sp == kp {
+			subs = append(subs, v...)
+		}
+	}
+	d.subs.lock.Unlock()
+	for _, s := range subs {
+		n := nodes
+		if s.path != kp {
+			var err error
+			if n, err = node.GetNodesByPath(nodes, s.path); err != nil {
+				continue
+			}
+		}
+		s.cb(d, s.path, n, meta)
+	}
+}
+
+// moveSubscriptions take the subscriptions of a previous registration of
+// the endpoint, they are served by the restored observations
+func (d *Device) moveSubscriptions(old *Device) {
+	old.subs.lock.Lock()
+	defer old.subs.lock.Unlock()
+	old.subs.d = d
+	d.subs = old.subs
+}
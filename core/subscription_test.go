@@ -0,0 +1,206 @@
+package core
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/plgd-dev/go-coap/v3/message"
+	"github.com/plgd-dev/go-coap/v3/message/codes"
+	"github.com/plgd-dev/go-coap/v3/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/yplam/lwm2m/node"
+)
+
+func TestAncestorPaths(t *testing.T) {
+	assert.Equal(t, []node.Path{
+		node.NewObjectPath(3),
+		node.NewObjectInstancePath(3, 0),
+		node.NewResourcePath(3, 0, 7),
+		node.NewResourceInstancePath(3, 0, 7, 1),
+	}, ancestorPaths(node.NewResourceInstancePath(3, 0, 7, 1)))
+	assert.Equal(t, []node.Path{node.NewObjectPath(3)}, ancestorPaths(node.NewObjectPath(3)))
+}
+
+func TestSubscribe(t *testing.T) {
+	reqs := make(chan testRequest, 10)
+	d := newTestDevice(t, func(w mux.ResponseWriter, r *mux.Message) {
+		req := newTestRequest(r)
+		reqs <- req
+		if req.observe != 0 {
+			_ = w.SetResponse(codes.Content, message.TextPlain, nil)
+			return
+		}
+		switch req.path {
+		case "/3303/0/5700":
+			_ = w.SetResponse(codes.Content, message.AppSenmlJSON, bytes.NewReader([]byte(
+				`[{"bn":"/3303/0/5700","v":21.5}]`)))
+		case "/3303/0":
+			_ = w.SetResponse(codes.Content, message.AppSenmlJSON, bytes.NewReader([]byte(
+				`[{"bn":"/3303/0/","n":"5700","v":22.5},{"n":"5701","vs":"Cel"}]`)))
+		default:
+			_ = w.SetResponse(codes.NotFound, message.TextPlain, nil)
+			return
+		}
+		w.Message().SetObserve(2)
+	})
+	d.SetMediaTypes(message.AppSenmlJSON, message.AppSenmlJSON)
+	nextReq := func() testRequest {
+		select {
+		case req := <-reqs:
+			return req
+		case <-time.After(5 * time.Second):
+			t.Fatal("no request")
+		}
+		return testRequest{}
+	}
+	type notification struct {
+		p     node.Path
+		value any
+		count int
+	}
+	notify := func(ch chan notification) ObserveFunc {
+		return func(d *Device, p node.Path, nodes []node.Node) {
+			n := notification{p: p, count: len(nodes)}
+			if res, err := node.GetResourceByPath(nodes, node.NewResourcePath(3303, 0, 5700)); err == nil {
+				ins, _ := res.GetInstance(0)
+				n.value = ins.Value()
+			}
+			ch <- n
+		}
+	}
+	next := func(ch chan notification) notification {
+		select {
+		case n := <-ch:
+			return n
+		case <-time.After(5 * time.Second):
+			t.Fatal("no notification")
+		}
+		return notification{}
+	}
+
+	res := node.NewResourcePath(3303, 0, 5700)
+	ch1 := make(chan notification, 10)
+	s1, err := d.Subscribe(testContext(t), res, notify(ch1))
+	assert.Nil(t, err)
+	assert.Equal(t, res, s1.Path())
+	req := nextReq()
+	assert.Equal(t, "/3303/0/5700", req.path)
+	assert.Equal(t, 0, req.observe)
+	assert.Equal(t, notification{p: res, value: 21.5, count: 1}, next(ch1))
+
+	// a second subscription share the observation
+	ch2 := make(chan notification, 10)
+	s2, err := d.Subscribe(testContext(t), res, notify(ch2))
+	assert.Nil(t, err)
+
+	// an ancestor subscription serve the others, the resource observation
+	// is canceled
+	ins := node.NewObjectInstancePath(3303, 0)
+	ch3 := make(chan notification, 10)
+	s3, err := d.Subscribe(testContext(t), ins, notify(ch3))
+	assert.Nil(t, err)
+	req = nextReq()
+	assert.Equal(t, "/3303/0", req.path)
+	assert.Equal(t, 0, req.observe)
+	req = nextReq()
+	assert.Equal(t, "/3303/0/5700", req.path)
+	assert.Equal(t, 1, req.observe)
+	assert.Equal(t, notification{p: ins, value: 22.5, count: 2}, next(ch3))
+	assert.Equal(t, notification{p: res, value: 22.5, count: 1}, next(ch1))
+	assert.Equal(t, notification{p: res, value: 22.5, count: 1}, next(ch2))
+
+	// the observation is canceled with the last subscription
+	assert.Nil(t, s3.Close())
+	assert.Nil(t, s1.Close())
+	assert.Nil(t, s1.Close())
+	assert.Equal(t, 0, len(reqs))
+	assert.Nil(t, s2.Close())
+	req = nextReq()
+	assert.Equal(t, "/3303/0", req.path)
+	assert.Equal(t, 1, req.observe)
+	_, ok := d.observations.Load(ins)
+	assert.False(t, ok)
+
+	// failed subscriptions are not kept
+	_, err = d.Subscribe(testContext(t), node.NewResourcePath(3303, 0, 5701), notify(ch1))
+	assert.NotNil(t, err)
+	assert.Equal(t, 0, len(d.subs.byPath))
+	_, err = d.Subscribe(testContext(t), node.NewRootPath(), notify(ch1))
+	assert.Equal(t, node.ErrPathInvalidValue, err)
+}
+
+func TestSubscribeWithObserve(t *testing.T) {
+	d := newTestDevice(t, func(w mux.ResponseWriter, r *mux.Message) {
+		_ = w.SetResponse(codes.Content, message.AppSenmlJSON, bytes.NewReader([]byte(
+			`[{"bn":"/3/0/9","v":95}]`)))
+		if newTestRequest(r).observe == 0 {
+			w.Message().SetObserve(2)
+		}
+	})
+	d.SetMediaTypes(message.AppSenmlJSON, message.AppSenmlJSON)
+	p := node.NewResourcePath(3, 0, 9)
+	cb := func(d *Device, p node.Path, notify []node.Node) {}
+	s, err := d.Subscribe(testContext(t), p, cb)
+	assert.Nil(t, err)
+
+	// Observe and CancelObserve keep the observation of the subscription
+	assert.Nil(t, d.Observe(p, cb))
+	assert.True(t, d.isEstablished(p))
+	assert.Nil(t, d.CancelObserve(p))
+	assert.True(t, d.isEstablished(p))
+	assert.Equal(t, ErrNotFound, d.CancelObserve(p))
+
+	assert.Nil(t, s.Close())
+	_, ok := d.observations.Load(p)
+	assert.False(t, ok)
+}
+
+func TestSubscribePending(t *testing.T) {
+	reqs := make(chan testRequest, 10)
+	d := newTestDevice(t, func(w mux.ResponseWriter, r *mux.Message) {
+		req := newTestRequest(r)
+		reqs <- req
+		_ = w.SetResponse(codes.Content, message.AppSenmlJSON, bytes.NewReader([]byte(
+			`[{"bn":"/3/0/9","v":95}]`)))
+		if req.observe == 0 {
+			w.Message().SetObserve(2)
+		}
+	})
+	d.SetMediaTypes(message.AppSenmlJSON, message.AppSenmlJSON)
+	d.Queue = true
+	p := node.NewResourcePath(3, 0, 9)
+	cb := func(d *Device, p node.Path, notify []node.Node) {}
+
+	// the request wait for the device to wake up
+	errs := make(chan error, 1)
+	go func() {
+		_, err := d.Subscribe(testContext(t), p, cb)
+		errs <- err
+	}()
+	assert.Eventually(t, func() bool {
+		return d.isPending(p)
+	}, time.Second, time.Millisecond)
+
+	// the pending observation is not sent again, and the subscriptions are
+	// not blocked meanwhile
+	done := make(chan struct{})
+	go func() {
+		d.initOrUpdateObservation()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("pending observation sent again")
+	}
+	s, err := d.Subscribe(testContext(t), p, cb)
+	assert.Nil(t, err)
+	assert.Nil(t, s.Close())
+
+	d.MarkAwake()
+	assert.Nil(t, <-errs)
+	assert.True(t, d.isEstablished(p))
+	assert.False(t, d.isPending(p))
+	assert.Equal(t, 1, len(reqs))
+}
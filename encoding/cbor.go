@@ -0,0 +1,452 @@
+package encoding
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"sort"
+	"unicode/utf8"
+)
+
+var (
+	ErrCborInvalid     = errors.New("invalid cbor data")
+	ErrCborUnsupported = errors.New("unsupported cbor data item")
+)
+
+// CBOR major types, defines in RFC8949
+const (
+	cborMajorUnsigned byte = 0
+	cborMajorNegative byte = 1
+	cborMajorBytes    byte = 2
+	cborMajorText     byte = 3
+	cborMajorArray    byte = 4
+	cborMajorMap      byte = 5
+	cborMajorTag      byte = 6
+	cborMajorSimple   byte = 7
+)
+
+const (
+	cborFalse   byte = 0xf4
+	cborTrue    byte = 0xf5
+	cborNull    byte = 0xf6
+	cborFloat32 byte = 0xfa
+	cborFloat64 byte = 0xfb
+	cborBreak   byte = 0xff
+
+	cborIndefinite byte = 31
+	cborMaxDepth        = 32
+)
+
+// CborPair is a key/value pair of a CBOR map
+type CborPair struct {
+	Key   any
+	Value any
+}
+
+// CborMap is a CBOR map kept as pairs in payload order, keys may be of any
+// type, e.g. arrays in LWM2M CBOR
+type CborMap []CborPair
+
+// Get return the value of the first pair with key k
+func (m CborMap) Get(k any) (any, bool) {
+	for _, p := range m {
+		if p.Key == k {
+			return p.Value, true
+		}
+	}
+	return nil, false
+}
+
+// CborTag is a tagged CBOR data item, e.g. tag 1 for epoch based time
+type CborTag struct {
+	Number  uint64
+	Content any
+}
+
+// DecodeCbor decode one CBOR data item, data must not contain trailing bytes.
+//
+// Data items are decoded to Go values:
+//
+//	unsigned and negative integers -> int64, uint64 if greater than math.MaxInt64
+//	floats (half, single, double)  -> float64
+//	byte strings                   -> []byte
+//	text strings                   -> string
+//	arrays                         -> []any
+//	maps                           -> CborMap
+//	tags                           -> CborTag
+//	true, false                    -> bool
+//	null, undefined                -> nil
+func DecodeCbor(data []byte) (any, error) {
+	d := &cborDecoder{data: data}
+	v, err := d.decode(0)
+	if err != nil {
+		return nil, err
+	}
+	if d.off != len(d.data) {
+		return nil, ErrCborInvalid
+	}
+	return v, nil
+}
+
+type cborDecoder struct {
+	data []byte
+	off  int
+}
+
+func (d *cborDecoder) head() (major byte, info byte, arg uint64, err error) {
+	if d.off >= len(d.data) {
+		err = ErrNotEnoughData
+		return
+	}
+	b := d.data[d.off]
+	d.off++
+	major = b >> 5
+	info = b & 0x1f
+	switch {
+	case info < 24:
+		arg = uint64(info)
+	case info <= 27:
+		n := 1 << (info - 24)
+		if d.off+n > len(d.data) {
+			err = ErrNotEnoughData
+			return
+		}
+		for _, c := range d.data[d.off : d.off+n] {
+			arg = arg<<8 | uint64(c)
+		}
+		d.off += n
+	case info == cborIndefinite:
+		if major == cborMajorUnsigned || major == cborMajorNegative || major == cborMajorTag {
+			err = ErrCborInvalid
+		}
+	default:
+		err = ErrCborInvalid
+	}
+	return
+}
+
+// isBreak consume the break stop code of an indefinite length item
+func (d *cborDecoder) isBreak() bool {
+	if d.off < len(d.data) && d.data[d.off] == cborBreak {
+		d.off++
+		return true
+	}
+	return false
+}
+
+func (d *cborDecoder) bytes(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)-d.off) {
+		return nil, ErrNotEnoughData
+	}
+	b := d.data[d.off : d.off+int(n)]
+	d.off += int(n)
+	return b, nil
+}
+
+// chunks read an indefinite length byte or text string
+func (d *cborDecoder) chunks(major byte) ([]byte, error) {
+	b := make([]byte, 0)
+	for !d.isBreak() {
+		m, info, arg, err := d.head()
+		if err != nil {
+			return nil, err
+		}
+		if m != major || info == cborIndefinite {
+			return nil, ErrCborInvalid
+		}
+		c, err := d.bytes(arg)
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, c...)
+	}
+	return b, nil
+}
+
+func (d *cborDecoder) decode(depth int) (any, error) {
+	if depth > cborMaxDepth {
+		return nil, ErrCborUnsupported
+	}
+	major, info, arg, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+	switch major {
+	case cborMajorUnsigned:
+		if arg > math.MaxInt64 {
+			return arg, nil
+		}
+		return int64(arg), nil
+	case cborMajorNegative:
+		if arg > math.MaxInt64 {
+			return nil, ErrCborUnsupported
+		}
+		return -1 - int64(arg), nil
+	case cborMajorBytes, cborMajorText:
+		var b []byte
+		if info == cborIndefinite {
+			b, err = d.chunks(major)
+		} else {
+			b, err = d.bytes(arg)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if major == cborMajorText {
+			if !utf8.Valid(b) {
+				return nil, ErrCborInvalid
+			}
+			return string(b), nil
+		}
+		return append([]byte(nil), b...), nil
+	case cborMajorArray:
+		arr := make([]any, 0)
+		for i := uint64(0); info == cborIndefinite || i < arg; i++ {
+			if info == cborIndefinite && d.isBreak() {
+				break
+			}
+			v, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		return arr, nil
+	case cborMajorMap:
+		m := make(CborMap, 0)
+		seen := make(map[any]bool)
+		for i := uint64(0); info == cborIndefinite || i < arg; i++ {
+			if info == cborIndefinite && d.isBreak() {
+				break
+			}
+			k, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			switch k.(type) {
+			case int64, uint64, string, bool, float64:
+				if seen[k] {
+					return nil, ErrCborInvalid
+				}
+				seen[k] = true
+			}
+			v, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			m = append(m, CborPair{Key: k, Value: v})
+		}
+		return m, nil
+	case cborMajorTag:
+		v, err := d.decode(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return CborTag{Number: arg, Content: v}, nil
+	case cborMajorSimple:
+		switch info {
+		case 20:
+			return false, nil
+		case 21:
+			return true, nil
+		case 22, 23:
+			return nil, nil
+		case 25:
+			return halfToFloat64(uint16(arg)), nil
+		case 26:
+			return float64(math.Float32frombits(uint32(arg))), nil
+		case 27:
+			return math.Float64frombits(arg), nil
+		case cborIndefinite:
+			// unexpected break stop code
+			return nil, ErrCborInvalid
+		default:
+			return nil, ErrCborUnsupported
+		}
+	}
+	return nil, ErrCborInvalid
+}
+
+func halfToFloat64(h uint16) float64 {
+	exp := int(h>>10) & 0x1f
+	mant := float64(h & 0x3ff)
+	var val float64
+	switch exp {
+	case 0:
+		val = math.Ldexp(mant, -24)
+	case 31:
+		if mant == 0 {
+			val = math.Inf(1)
+		} else {
+			val = math.NaN()
+		}
+	default:
+		val = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		return -val
+	}
+	return val
+}
+
+// EncodeCbor encode a Go value to CBOR, supported types are the ones
+// returned by DecodeCbor, plus other sized integers, float32 and
+// map[any]any. A CborMap is encoded in its order, keys of a map[any]any
+// are sorted in deterministic order. Floats use the shortest of single
+// or double precision that keep the value
+func EncodeCbor(v any) ([]byte, error) {
+	e := &cborEncoder{}
+	if err := e.encode(v, 0); err != nil {
+		return nil, err
+	}
+	return e.buf, nil
+}
+
+type cborEncoder struct {
+	buf []byte
+}
+
+func (e *cborEncoder) head(major byte, arg uint64) {
+	major = major << 5
+	switch {
+	case arg < 24:
+		e.buf = append(e.buf, major|byte(arg))
+	case arg <= math.MaxUint8:
+		e.buf = append(e.buf, major|24, byte(arg))
+	case arg <= math.MaxUint16:
+		e.buf = append(e.buf, major|25)
+		e.appendUint(arg, 2)
+	case arg <= math.MaxUint32:
+		e.buf = append(e.buf, major|26)
+		e.appendUint(arg, 4)
+	default:
+		e.buf = append(e.buf, major|27)
+		e.appendUint(arg, 8)
+	}
+}
+
+func (e *cborEncoder) appendUint(v uint64, n int) {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	e.buf = append(e.buf, b[8-n:]...)
+}
+
+func (e *cborEncoder) int(v int64) {
+	if v < 0 {
+		e.head(cborMajorNegative, uint64(-1-v))
+	} else {
+		e.head(cborMajorUnsigned, uint64(v))
+	}
+}
+
+func (e *cborEncoder) float(v float64) {
+	if f32 := float32(v); float64(f32) == v || math.IsNaN(v) {
+		e.buf = append(e.buf, cborFloat32)
+		e.appendUint(uint64(math.Float32bits(f32)), 4)
+		return
+	}
+	e.buf = append(e.buf, cborFloat64)
+	e.appendUint(math.Float64bits(v), 8)
+}
+
+func (e *cborEncoder) encode(v any, depth int) error {
+	if depth > cborMaxDepth {
+		return ErrCborUnsupported
+	}
+	switch val := v.(type) {
+	case nil:
+		e.buf = append(e.buf, cborNull)
+	case bool:
+		if val {
+			e.buf = append(e.buf, cborTrue)
+		} else {
+			e.buf = append(e.buf, cborFalse)
+		}
+	case int:
+		e.int(int64(val))
+	case int8:
+		e.int(int64(val))
+	case int16:
+		e.int(int64(val))
+	case int32:
+		e.int(int64(val))
+	case int64:
+		e.int(val)
+	case uint:
+		e.head(cborMajorUnsigned, uint64(val))
+	case uint8:
+		e.head(cborMajorUnsigned, uint64(val))
+	case uint16:
+		e.head(cborMajorUnsigned, uint64(val))
+	case uint32:
+		e.head(cborMajorUnsigned, uint64(val))
+	case uint64:
+		e.head(cborMajorUnsigned, val)
+	case float32:
+		e.float(float64(val))
+	case float64:
+		e.float(val)
+	case string:
+		e.head(cborMajorText, uint64(len(val)))
+		e.buf = append(e.buf, val...)
+	case []byte:
+		e.head(cborMajorBytes, uint64(len(val)))
+		e.buf = append(e.buf, val...)
+	case []any:
+		e.head(cborMajorArray, uint64(len(val)))
+		for _, item := range val {
+			if err := e.encode(item, depth+1); err != nil {
+				return err
+			}
+		}
+	case CborMap:
+		e.head(cborMajorMap, uint64(len(val)))
+		for _, p := range val {
+			if err := e.encode(p.Key, depth+1); err != nil {
+				return err
+			}
+			if err := e.encode(p.Value, depth+1); err != nil {
+				return err
+			}
+		}
+	case map[any]any:
+		return e.encodeMap(val, depth)
+	case CborTag:
+		e.head(cborMajorTag, val.Number)
+		return e.encode(val.Content, depth+1)
+	default:
+		return ErrUnknownType
+	}
+	return nil
+}
+
+func (e *cborEncoder) encodeMap(m map[any]any, depth int) error {
+	type pair struct {
+		key []byte
+		val any
+	}
+	pairs := make([]pair, 0, len(m))
+	for k, v := range m {
+		ke := &cborEncoder{}
+		if err := ke.encode(k, depth+1); err != nil {
+			return err
+		}
+		pairs = append(pairs, pair{key: ke.buf, val: v})
+	}
+	// length-first deterministic order, see RFC7049 section 3.9
+	sort.Slice(pairs, func(i, j int) bool {
+		if len(pairs[i].key) != len(pairs[j].key) {
+			return len(pairs[i].key) < len(pairs[j].key)
+		}
+		return bytes.Compare(pairs[i].key, pairs[j].key) < 0
+	})
+	e.head(cborMajorMap, uint64(len(pairs)))
+	for _, p := range pairs {
+		e.buf = append(e.buf, p.key...)
+		if err := e.encode(p.val, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package encoding
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeCbor(t *testing.T) {
+	// test vectors from RFC8949 Appendix A
+	tests := []struct {
+		hex string
+		val any
+	}{
+		{"00", int64(0)},
+		{"17", int64(23)},
+		{"1818", int64(24)},
+		{"1903e8", int64(1000)},
+		{"1b000000e8d4a51000", int64(1000000000000)},
+		{"1bffffffffffffffff", uint64(18446744073709551615)},
+		{"20", int64(-1)},
+		{"3903e7", int64(-1000)},
+		{"f93c00", 1.0},
+		{"f9c400", -4.0},
+		{"f90001", 5.960464477539063e-08},
+		{"fa47c35000", 100000.0},
+		{"fb3ff199999999999a", 1.1},
+		{"f4", false},
+		{"f5", true},
+		{"f6", nil},
+		{"4401020304", []byte{0x01, 0x02, 0x03, 0x04}},
+		{"6449455446", "IETF"},
+		{"83010203", []any{int64(1), int64(2), int64(3)}},
+		{"9f018202039f0405ffff", []any{int64(1), []any{int64(2), int64(3)}, []any{int64(4), int64(5)}}},
+		{"a201020304", CborMap{{int64(1), int64(2)}, {int64(3), int64(4)}}},
+		{"bf61610161629f0203ffff", CborMap{{"a", int64(1)}, {"b", []any{int64(2), int64(3)}}}},
+		{"a1820102f5", CborMap{{[]any{int64(1), int64(2)}, true}}},
+		{"5f42010243030405ff", []byte{0x01, 0x02, 0x03, 0x04, 0x05}},
+		{"7f657374726561646d696e67ff", "streaming"},
+		{"c11a514b67b0", CborTag{Number: 1, Content: int64(1363896240)}},
+	}
+	for _, tt := range tests {
+		data, err := _strToByte(tt.hex)
+		assert.Nil(t, err)
+		v, err := DecodeCbor(data)
+		assert.Nil(t, err, tt.hex)
+		assert.Equal(t, tt.val, v, tt.hex)
+	}
+
+	v, err := DecodeCbor([]byte{0xf9, 0x7c, 0x00})
+	assert.Nil(t, err)
+	assert.True(t, math.IsInf(v.(float64), 1))
+
+	invalid := []string{
+		"",
+		"18",              // missing argument
+		"62",              // missing text
+		"8301",            // short array
+		"a20102",          // short map
+		"0001",            // trailing data
+		"ff",              // unexpected break
+		"a2010201" + "02", // duplicate key
+		"62c328",          // invalid utf-8
+		"5f6161ff",        // text chunk in byte string
+	}
+	for _, h := range invalid {
+		data, err := _strToByte(h)
+		assert.Nil(t, err)
+		_, err = DecodeCbor(data)
+		assert.NotNil(t, err, h)
+	}
+}
+
+func TestEncodeCbor(t *testing.T) {
+	tests := []struct {
+		val any
+		hex string
+	}{
+		{0, "00"},
+		{uint16(1000), "1903e8"},
+		{int64(-1000), "3903e7"},
+		{uint64(18446744073709551615), "1bffffffffffffffff"},
+		{100000.0, "fa47c35000"},
+		{1.1, "fb3ff199999999999a"},
+		{true, "f5"},
+		{nil, "f6"},
+		{"IETF", "6449455446"},
+		{[]byte{0x01, 0x02}, "420102"},
+		{[]any{1, "a"}, "82016161"},
+		{map[any]any{"b": 1, 10: 2, -1: 3, "a": 4}, "a40a0220036161046162" + "01"},
+		{CborTag{Number: 1, Content: 1363896240}, "c11a514b67b0"},
+		{CborMap{{"b", 1}, {[]any{1, 2}, 2}}, "a2616201820102" + "02"},
+	}
+	for _, tt := range tests {
+		data, err := EncodeCbor(tt.val)
+		assert.Nil(t, err)
+		expected, _ := _strToByte(tt.hex)
+		assert.Equal(t, expected, data, tt.hex)
+	}
+	_, err := EncodeCbor(struct{}{})
+	assert.Equal(t, ErrUnknownType, err)
+}
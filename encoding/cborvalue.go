@@ -0,0 +1,86 @@
+package encoding
+
+import (
+	"time"
+)
+
+// CborValue is a single resource value encoded as one CBOR data item
+// (content-format 60), Value hold the encoded bytes.
+//
+// Time may be an integer or an epoch based date/time (tag 1),
+// Objlnk is represented as text "ObjectID:InstanceID"
+type CborValue struct {
+	Value []byte
+}
+
+// typed decode the data item to a TypedValue, tagged time is unwrapped
+func (c *CborValue) typed() *TypedValue {
+	v, err := DecodeCbor(c.Value)
+	if err != nil {
+		return &TypedValue{}
+	}
+	if t, ok := v.(CborTag); ok && t.Number == cborTagEpochTime {
+		v = t.Content
+	}
+	return &TypedValue{Value: v}
+}
+
+func (c *CborValue) StringVal() string {
+	return c.typed().StringVal()
+}
+
+func (c *CborValue) Integer() (val int64, err error) {
+	return c.typed().Integer()
+}
+
+func (c *CborValue) Unsigned() (val uint64, err error) {
+	return c.typed().Unsigned()
+}
+
+func (c *CborValue) Float() (float64, error) {
+	return c.typed().Float()
+}
+
+func (c *CborValue) Boolean() (val bool, err error) {
+	return c.typed().Boolean()
+}
+
+func (c *CborValue) Opaque() []byte {
+	return c.typed().Opaque()
+}
+
+func (c *CborValue) Time() (int64, error) {
+	return c.typed().Time()
+}
+
+func (c *CborValue) ObjectLink() (uint16, uint16, error) {
+	return c.typed().ObjectLink()
+}
+
+func (c *CborValue) Raw() []byte {
+	return c.Value
+}
+
+func NewCborRaw(data []byte) *CborValue {
+	return &CborValue{Value: data}
+}
+
+func NewCborValue(val any) (*CborValue, error) {
+	var item any
+	switch v := val.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64,
+		float32, float64, bool, string, []byte:
+		item = v
+	case time.Time:
+		item = CborTag{Number: cborTagEpochTime, Content: v.Unix()}
+	case ObjLink:
+		item = v.String()
+	default:
+		return nil, ErrUnknownType
+	}
+	data, err := EncodeCbor(item)
+	if err != nil {
+		return nil, err
+	}
+	return &CborValue{Value: data}, nil
+}
@@ -0,0 +1,95 @@
+package encoding
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCborValue(t *testing.T) {
+	{ // int
+		val, err := NewCborValue(42)
+		assert.Nil(t, err)
+		assert.Equal(t, []byte{0x18, 0x2a}, val.Raw())
+		i, err := val.Integer()
+		assert.Nil(t, err)
+		assert.Equal(t, int64(42), i)
+		f, err := val.Float()
+		assert.Nil(t, err)
+		assert.Equal(t, 42.0, f)
+	}
+	{ // negative int
+		val, err := NewCborValue(int16(-500))
+		assert.Nil(t, err)
+		assert.Equal(t, []byte{0x39, 0x01, 0xf3}, val.Raw())
+		i, err := val.Integer()
+		assert.Nil(t, err)
+		assert.Equal(t, int64(-500), i)
+	}
+	{ // unsigned
+		val, err := NewCborValue(uint64(math.MaxUint64))
+		assert.Nil(t, err)
+		assert.Equal(t, []byte{0x1b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, val.Raw())
+		_, err = val.Integer()
+		assert.NotNil(t, err)
+		assert.Equal(t, "18446744073709551615", val.StringVal())
+	}
+	{ // float
+		val, err := NewCborValue(3.14151617181920)
+		assert.Nil(t, err)
+		assert.Equal(t, []byte{0xfb, 0x40, 0x09, 0x21, 0xd3, 0x3b, 0xe, 0x8c, 0x74}, val.Raw())
+		f, err := val.Float()
+		assert.Nil(t, err)
+		assert.Equal(t, 3.14151617181920, f)
+		_, err = val.Integer()
+		assert.NotNil(t, err)
+	}
+	{ // bool
+		val, err := NewCborValue(true)
+		assert.Nil(t, err)
+		assert.Equal(t, []byte{0xf5}, val.Raw())
+		b, err := val.Boolean()
+		assert.Nil(t, err)
+		assert.True(t, b)
+	}
+	{ // string
+		val, err := NewCborValue("hello")
+		assert.Nil(t, err)
+		assert.Equal(t, []byte{0x65, 0x68, 0x65, 0x6c, 0x6c, 0x6f}, val.Raw())
+		assert.Equal(t, "hello", val.StringVal())
+	}
+	{ // opaque
+		val, err := NewCborValue([]byte{0x01, 0x02, 0x03, 0x04})
+		assert.Nil(t, err)
+		assert.Equal(t, []byte{0x44, 0x01, 0x02, 0x03, 0x04}, val.Raw())
+		assert.Equal(t, []byte{0x01, 0x02, 0x03, 0x04}, val.Opaque())
+	}
+	{ // time
+		val, err := NewCborValue(time.Unix(1363896240, 0))
+		assert.Nil(t, err)
+		assert.Equal(t, []byte{0xc1, 0x1a, 0x51, 0x4b, 0x67, 0xb0}, val.Raw())
+		tm, err := val.Time()
+		assert.Nil(t, err)
+		assert.Equal(t, int64(1363896240), tm)
+		tm, err = NewCborRaw([]byte{0x1a, 0x51, 0x4b, 0x67, 0xb0}).Time()
+		assert.Nil(t, err)
+		assert.Equal(t, int64(1363896240), tm)
+	}
+	{ // objlnk
+		val, err := NewCborValue(ObjLink{ObjectId: 3, InstanceId: 1})
+		assert.Nil(t, err)
+		assert.Equal(t, []byte{0x63, 0x33, 0x3a, 0x31}, val.Raw())
+		o, i, err := val.ObjectLink()
+		assert.Nil(t, err)
+		assert.Equal(t, uint16(3), o)
+		assert.Equal(t, uint16(1), i)
+	}
+	{ // invalid
+		_, err := NewCborValue(struct{}{})
+		assert.Equal(t, ErrUnknownType, err)
+		_, err = NewCborRaw([]byte{0x18}).Integer()
+		assert.NotNil(t, err)
+	}
+}
@@ -2,7 +2,7 @@ package encoding
 
 import (
 	"errors"
-	"regexp"
+	"fmt"
 	"strings"
 )
 
@@ -10,54 +10,127 @@ var (
 	ErrCoreLinkInvalidValue = errors.New("invalid core link string value")
 )
 
+// CoreLinkError report the position of a syntax error in a link format
+// string, it matches ErrCoreLinkInvalidValue with errors.Is
+type CoreLinkError struct {
+	Offset int
+	Msg    string
+}
+
+func (e *CoreLinkError) Error() string {
+	return fmt.Sprintf("invalid core link at offset %d: %s", e.Offset, e.Msg)
+}
+
+func (e *CoreLinkError) Unwrap() error {
+	return ErrCoreLinkInvalidValue
+}
+
+// CoreLinkParam is a link parameter, a parameter without value like
+// ";obs" is a flag, Quoted is set if the value was a quoted-string
+type CoreLinkParam struct {
+	Key      string
+	Value    string
+	HasValue bool
+	Quoted   bool
+}
+
+func (p CoreLinkParam) String() string {
+	if !p.HasValue {
+		return p.Key
+	}
+	if !p.Quoted && isPtoken(p.Value) && p.Key != "anchor" && p.Key != "title" {
+		return p.Key + "=" + p.Value
+	}
+	var b strings.Builder
+	b.WriteString(p.Key)
+	b.WriteString("=\"")
+	for _, c := range p.Value {
+		if c == '"' || c == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
 // CoreLink is a link format use by Coap.
 // Defines in RFC6690
-// LWM2M use CoreLink to discover objects
+// LWM2M use CoreLink to discover objects, Params keep the order of the
+// link-params
 type CoreLink struct {
 	Uri    string
-	Params map[string]string
+	Params []CoreLinkParam
 }
 
 func NewCoreLink() *CoreLink {
 	return &CoreLink{
-		Params: make(map[string]string),
+		Params: make([]CoreLinkParam, 0),
+	}
+}
+
+// Param return the value of the first parameter named key, a flag
+// parameter has an empty value
+func (l *CoreLink) Param(key string) (string, bool) {
+	for _, p := range l.Params {
+		if p.Key == key {
+			return p.Value, true
+		}
 	}
+	return "", false
 }
 
+// SetParam set the value of parameter key, it is appended if not exists
 func (l *CoreLink) SetParam(key string, val string) {
-	l.Params[key] = val
+	for i, p := range l.Params {
+		if p.Key == key {
+			l.Params[i].Value = val
+			l.Params[i].HasValue = true
+			return
+		}
+	}
+	l.Params = append(l.Params, CoreLinkParam{Key: key, Value: val, HasValue: true})
 }
 
-func (l *CoreLink) UnmarshalText(text []byte) error {
-	if len(text) < 3 {
-		return ErrCoreLinkInvalidValue
-	}
-	str := string(text)
-	var elemRe = regexp.MustCompile(`<[^>]*>`)
-	elemMatch := elemRe.FindString(str)
-	if len(elemMatch) < 3 {
-		return ErrCoreLinkInvalidValue
-	}
-	l.Uri = elemMatch[1 : len(elemMatch)-1]
-	if len(text) > len(elemMatch) {
-		attrs := strings.Split(str[len(elemMatch)+1:], ";")
-		for _, attr := range attrs {
-			pair := strings.Split(attr, "=")
-			if len(pair) != 2 || len(pair[0]) == 0 {
-				return ErrCoreLinkInvalidValue
-			}
-			l.Params[pair[0]] = strings.Replace(pair[1], "\"", "", -1)
+// SetFlag add a parameter without value, e.g. ";obs"
+func (l *CoreLink) SetFlag(key string) {
+	for i, p := range l.Params {
+		if p.Key == key {
+			l.Params[i] = CoreLinkParam{Key: key}
+			return
 		}
 	}
+	l.Params = append(l.Params, CoreLinkParam{Key: key})
+}
+
+func (l *CoreLink) UnmarshalText(text []byte) error {
+	p := &coreLinkParser{s: string(text)}
+	link, err := p.link()
+	if err != nil {
+		return err
+	}
+	p.skipSpace()
+	if !p.eof() {
+		return p.errorf("unexpected %q after link", p.s[p.off])
+	}
+	*l = *link
 	return nil
 }
 
+func (l *CoreLink) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
 func (l *CoreLink) String() string {
-	str := "<" + l.Uri + ">"
-	for key, val := range l.Params {
-		str += ";" + key + "=\"" + val + "\""
+	var b strings.Builder
+	b.WriteString("<")
+	b.WriteString(l.Uri)
+	b.WriteString(">")
+	for _, p := range l.Params {
+		b.WriteString(";")
+		b.WriteString(p.String())
 	}
-	return str
+	return b.String()
 }
 
 //	A CoRE resource discovery response may contain multiple CoreLink values
@@ -108,14 +181,182 @@ func (l *CoreLink) String() string {
 //    ext-value      = <defined in [RFC5987]>
 //    parmname       = <defined in [RFC5987]>
 
+// CoreLinksFromString parse a link format payload, whitespace around
+// separators is ignored, an empty payload has no links
 func CoreLinksFromString(s string) (links []*CoreLink, err error) {
-	var re = regexp.MustCompile(`(<[^>]+>\s*(;\s*\w+\s*(=\s*(\w+|"([^"\\]*(\\.[^"\\]*)*)")\s*)?)*)`)
-	m := re.FindAllString(s, -1)
-	for _, match := range m {
-		l := NewCoreLink()
-		if err = l.UnmarshalText([]byte(match)); err == nil {
-			links = append(links, l)
+	p := &coreLinkParser{s: s}
+	links = make([]*CoreLink, 0)
+	p.skipSpace()
+	if p.eof() {
+		return links, nil
+	}
+	for {
+		l, err := p.link()
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, l)
+		p.skipSpace()
+		if p.eof() {
+			return links, nil
+		}
+		if p.s[p.off] != ',' {
+			return nil, p.errorf("expect ',' between links, got %q", p.s[p.off])
+		}
+		p.off++
+		p.skipSpace()
+	}
+}
+
+// CoreLinksToString serialize links to a link format payload
+func CoreLinksToString(links []*CoreLink) string {
+	strs := make([]string, 0, len(links))
+	for _, l := range links {
+		strs = append(strs, l.String())
+	}
+	return strings.Join(strs, ",")
+}
+
+type coreLinkParser struct {
+	s   string
+	off int
+}
+
+func (p *coreLinkParser) errorf(format string, args ...any) error {
+	return &CoreLinkError{Offset: p.off, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *coreLinkParser) eof() bool {
+	return p.off >= len(p.s)
+}
+
+func (p *coreLinkParser) skipSpace() {
+	for !p.eof() && (p.s[p.off] == ' ' || p.s[p.off] == '\t' || p.s[p.off] == '\r' || p.s[p.off] == '\n') {
+		p.off++
+	}
+}
+
+// link parse link-value = "<" URI-Reference ">" *( ";" link-param )
+func (p *coreLinkParser) link() (*CoreLink, error) {
+	if p.eof() || p.s[p.off] != '<' {
+		return nil, p.errorf("expect '<'")
+	}
+	p.off++
+	end := strings.IndexByte(p.s[p.off:], '>')
+	if end < 0 {
+		return nil, p.errorf("missing '>'")
+	}
+	l := NewCoreLink()
+	l.Uri = p.s[p.off : p.off+end]
+	if strings.ContainsAny(l.Uri, "<\" \t\r\n") {
+		return nil, p.errorf("invalid character in uri %q", l.Uri)
+	}
+	p.off += end + 1
+	for {
+		p.skipSpace()
+		if p.eof() || p.s[p.off] != ';' {
+			return l, nil
+		}
+		p.off++
+		p.skipSpace()
+		param, err := p.param()
+		if err != nil {
+			return nil, err
+		}
+		l.Params = append(l.Params, param)
+	}
+}
+
+// param parse link-param = parmname [ "=" ( ptoken / quoted-string ) ]
+func (p *coreLinkParser) param() (CoreLinkParam, error) {
+	start := p.off
+	for !p.eof() && isParmnameChar(p.s[p.off]) {
+		p.off++
+	}
+	if start == p.off {
+		if p.eof() {
+			return CoreLinkParam{}, p.errorf("expect parameter name")
+		}
+		return CoreLinkParam{}, p.errorf("invalid character %q in parameter name", p.s[p.off])
+	}
+	param := CoreLinkParam{Key: p.s[start:p.off]}
+	p.skipSpace()
+	if p.eof() || p.s[p.off] != '=' {
+		return param, nil
+	}
+	p.off++
+	p.skipSpace()
+	param.HasValue = true
+	if !p.eof() && p.s[p.off] == '"' {
+		v, err := p.quotedString()
+		if err != nil {
+			return CoreLinkParam{}, err
+		}
+		param.Value = v
+		param.Quoted = true
+		return param, nil
+	}
+	start = p.off
+	for !p.eof() && isPtokenChar(p.s[p.off]) {
+		p.off++
+	}
+	if start == p.off {
+		return CoreLinkParam{}, p.errorf("expect value of parameter %q", param.Key)
+	}
+	param.Value = p.s[start:p.off]
+	return param, nil
+}
+
+func (p *coreLinkParser) quotedString() (string, error) {
+	start := p.off
+	p.off++
+	var b strings.Builder
+	for !p.eof() {
+		c := p.s[p.off]
+		switch c {
+		case '"':
+			p.off++
+			return b.String(), nil
+		case '\\':
+			if p.off+1 >= len(p.s) {
+				p.off = start
+				return "", p.errorf("unterminated quoted string")
+			}
+			b.WriteByte(p.s[p.off+1])
+			p.off += 2
+		default:
+			b.WriteByte(c)
+			p.off++
+		}
+	}
+	p.off = start
+	return "", p.errorf("unterminated quoted string")
+}
+
+// isParmnameChar report whether c is an attr-char of RFC5987 or the "*"
+// of an ext-name-star
+func isParmnameChar(c byte) bool {
+	if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' {
+		return true
+	}
+	return strings.IndexByte("!#$&+-.^_`|~*", c) >= 0
+}
+
+func isPtokenChar(c byte) bool {
+	if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' {
+		return true
+	}
+	return strings.IndexByte("!#$%&'()*+-./:<=>?@[]^_`{|}~", c) >= 0
+}
+
+func isPtoken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isPtokenChar(s[i]) {
+			return false
 		}
 	}
-	return
+	return true
 }
@@ -14,3 +14,66 @@ func TestCoreLinksFromString(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, 12, len(links))
 }
+
+func TestCoreLinkParams(t *testing.T) {
+	links, err := CoreLinksFromString(` </3/0> ; obs ; title="a,b;\"c\"" , </3/0/1>;pmin=10;pmax=60,
+</1>;ver=1.1 `)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(links))
+	assert.Equal(t, "/3/0", links[0].Uri)
+	assert.Equal(t, []CoreLinkParam{
+		{Key: "obs"},
+		{Key: "title", Value: `a,b;"c"`, HasValue: true, Quoted: true},
+	}, links[0].Params)
+	v, ok := links[0].Param("obs")
+	assert.True(t, ok)
+	assert.Equal(t, "", v)
+	v, ok = links[1].Param("pmax")
+	assert.True(t, ok)
+	assert.Equal(t, "60", v)
+	_, ok = links[1].Param("gt")
+	assert.False(t, ok)
+
+	assert.Equal(t, `</3/0>;obs;title="a,b;\"c\"",</3/0/1>;pmin=10;pmax=60,</1>;ver=1.1`,
+		CoreLinksToString(links))
+
+	l := NewCoreLink()
+	l.Uri = "/"
+	l.SetParam("rt", "oma.lwm2m")
+	l.SetParam("ct", "11543")
+	l.SetFlag("obs")
+	l.SetParam("rt", "oma.lwm2m.v2")
+	assert.Equal(t, `</>;rt=oma.lwm2m.v2;ct=11543;obs`, l.String())
+
+	links, err = CoreLinksFromString("")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(links))
+}
+
+func TestCoreLinkErrors(t *testing.T) {
+	cases := []struct {
+		s      string
+		offset int
+	}{
+		{`/3/0`, 0},
+		{`</3/0`, 1},
+		{`</3/0>;`, 7},
+		{`</3/0>;pmin=`, 12},
+		{`</3/0>;title="abc`, 13},
+		{`</3/0> </1>`, 7},
+		{`</3/0>,`, 7},
+		{`</3/0>;p min=1`, 9},
+	}
+	for _, c := range cases {
+		_, err := CoreLinksFromString(c.s)
+		assert.ErrorIs(t, err, ErrCoreLinkInvalidValue, c.s)
+		var le *CoreLinkError
+		if assert.ErrorAs(t, err, &le, c.s) {
+			assert.Equal(t, c.offset, le.Offset, c.s)
+		}
+	}
+	l := NewCoreLink()
+	assert.NotNil(t, l.UnmarshalText([]byte(`</1>,</2>`)))
+	assert.Nil(t, l.UnmarshalText([]byte(`</1>;ct="60"`)))
+	assert.Equal(t, `</1>;ct="60"`, l.String())
+}
@@ -1,17 +1,24 @@
 package encoding
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
 
 var (
 	ErrInvalidLength = errors.New("invalid data length")
 	ErrNotEnoughData = errors.New("not enough data")
 	ErrNotBoolean    = errors.New("not a boolean representation")
 	ErrUnknownType   = errors.New("unknown type")
+	ErrInvalidObjLnk = errors.New("wrong ObjLink text representation")
 )
 
 type Valuer interface {
 	StringVal() string
 	Integer() (val int64, err error)
+	Unsigned() (val uint64, err error)
 	Float() (float64, error)
 	Boolean() (val bool, err error)
 	Opaque() []byte
@@ -19,3 +26,30 @@ type Valuer interface {
 	ObjectLink() (uint16, uint16, error)
 	Raw() []byte
 }
+
+// ObjLink is the value of an Objlnk resource, a reference to an
+// Object Instance, textual representation is "ObjectID:InstanceID"
+type ObjLink struct {
+	ObjectId   uint16
+	InstanceId uint16
+}
+
+func (o ObjLink) String() string {
+	return fmt.Sprintf("%d:%d", o.ObjectId, o.InstanceId)
+}
+
+func ParseObjLink(s string) (ObjLink, error) {
+	splitted := strings.Split(s, ":")
+	if len(splitted) != 2 {
+		return ObjLink{}, ErrInvalidObjLnk
+	}
+	od, err := strconv.ParseUint(splitted[0], 10, 16)
+	if err != nil {
+		return ObjLink{}, err
+	}
+	oid, err := strconv.ParseUint(splitted[1], 10, 16)
+	if err != nil {
+		return ObjLink{}, err
+	}
+	return ObjLink{ObjectId: uint16(od), InstanceId: uint16(oid)}, nil
+}
@@ -0,0 +1,183 @@
+package encoding
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrExecuteArgsInvalidValue = errors.New("invalid execute arguments")
+)
+
+// ExecuteArgsError report the position of a syntax error in an execute
+// arguments string, it matches ErrExecuteArgsInvalidValue with errors.Is
+type ExecuteArgsError struct {
+	Offset int
+	Msg    string
+}
+
+func (e *ExecuteArgsError) Error() string {
+	return fmt.Sprintf("invalid execute arguments at offset %d: %s", e.Offset, e.Msg)
+}
+
+func (e *ExecuteArgsError) Unwrap() error {
+	return ErrExecuteArgsInvalidValue
+}
+
+// ExecuteArg is one argument of an Execute operation, an argument without
+// value like "1" has HasValue unset
+type ExecuteArg struct {
+	Digit    uint8
+	Value    string
+	HasValue bool
+}
+
+func (a ExecuteArg) String() string {
+	if !a.HasValue {
+		return fmt.Sprintf("%d", a.Digit)
+	}
+	return fmt.Sprintf("%d='%s'", a.Digit, a.Value)
+}
+
+// ExecuteArgs is the argument list of an Execute operation, defined in
+// LWM2M Technical Specification Core, 5.4.5:
+//
+//	arglist  = argument *( "," argument )
+//	argument = digit [ "=" "'" *argchar "'" ]
+//	digit    = %x30-39
+//	argchar  = %x21 / %x23-26 / %x28-5B / %x5D-7E
+//
+// Arguments are kept in ascending digit order
+type ExecuteArgs struct {
+	Args []ExecuteArg
+}
+
+func NewExecuteArgs() *ExecuteArgs {
+	return &ExecuteArgs{
+		Args: make([]ExecuteArg, 0),
+	}
+}
+
+// ParseExecuteArgs parse an execute arguments string, an empty string is an
+// empty argument list
+func ParseExecuteArgs(s string) (*ExecuteArgs, error) {
+	a := NewExecuteArgs()
+	if err := a.UnmarshalText([]byte(s)); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Get return the value of argument digit, an argument without value has an
+// empty value
+func (a *ExecuteArgs) Get(digit uint8) (string, bool) {
+	for _, arg := range a.Args {
+		if arg.Digit == digit {
+			return arg.Value, true
+		}
+	}
+	return "", false
+}
+
+// Set set the value of argument digit, digit must be 0 to 9 and value must
+// only contain printable ASCII characters other than "'" and "\"
+func (a *ExecuteArgs) Set(digit uint8, value string) error {
+	if digit > 9 {
+		return fmt.Errorf("argument %d: %w", digit, ErrExecuteArgsInvalidValue)
+	}
+	for i := 0; i < len(value); i++ {
+		if !isArgChar(value[i]) {
+			return fmt.Errorf("argument %d: invalid character %q: %w", digit, value[i], ErrExecuteArgsInvalidValue)
+		}
+	}
+	a.set(ExecuteArg{Digit: digit, Value: value, HasValue: true})
+	return nil
+}
+
+// SetFlag set argument digit without value
+func (a *ExecuteArgs) SetFlag(digit uint8) error {
+	if digit > 9 {
+		return fmt.Errorf("argument %d: %w", digit, ErrExecuteArgsInvalidValue)
+	}
+	a.set(ExecuteArg{Digit: digit})
+	return nil
+}
+
+func (a *ExecuteArgs) set(arg ExecuteArg) {
+	for i := range a.Args {
+		if a.Args[i].Digit == arg.Digit {
+			a.Args[i] = arg
+			return
+		}
+	}
+	a.Args = append(a.Args, arg)
+	sort.Slice(a.Args, func(i, j int) bool { return a.Args[i].Digit < a.Args[j].Digit })
+}
+
+func (a *ExecuteArgs) UnmarshalText(text []byte) error {
+	s := string(text)
+	args := make([]ExecuteArg, 0)
+	seen := make(map[uint8]bool)
+	errorf := func(off int, format string, v ...any) error {
+		return &ExecuteArgsError{Offset: off, Msg: fmt.Sprintf(format, v...)}
+	}
+	for off := 0; off < len(s); {
+		if s[off] < '0' || s[off] > '9' {
+			return errorf(off, "expect digit, got %q", s[off])
+		}
+		arg := ExecuteArg{Digit: s[off] - '0'}
+		if seen[arg.Digit] {
+			return errorf(off, "argument %d repeated", arg.Digit)
+		}
+		seen[arg.Digit] = true
+		off++
+		if off < len(s) && s[off] == '=' {
+			off++
+			if off >= len(s) || s[off] != '\'' {
+				return errorf(off, "expect \"'\" after \"=\"")
+			}
+			off++
+			start := off
+			for off < len(s) && isArgChar(s[off]) {
+				off++
+			}
+			if off >= len(s) || s[off] != '\'' {
+				return errorf(off, "unterminated value")
+			}
+			arg.Value = s[start:off]
+			arg.HasValue = true
+			off++
+		}
+		args = append(args, arg)
+		if off < len(s) {
+			if s[off] != ',' {
+				return errorf(off, "expect \",\", got %q", s[off])
+			}
+			off++
+			if off == len(s) {
+				return errorf(off, "expect argument after \",\"")
+			}
+		}
+	}
+	sort.Slice(args, func(i, j int) bool { return args[i].Digit < args[j].Digit })
+	a.Args = args
+	return nil
+}
+
+func (a *ExecuteArgs) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+func (a *ExecuteArgs) String() string {
+	s := make([]string, 0, len(a.Args))
+	for _, arg := range a.Args {
+		s = append(s, arg.String())
+	}
+	return strings.Join(s, ",")
+}
+
+func isArgChar(c byte) bool {
+	return c == 0x21 || (c >= 0x23 && c <= 0x26) || (c >= 0x28 && c <= 0x5b) || (c >= 0x5d && c <= 0x7e)
+}
@@ -0,0 +1,65 @@
+package encoding
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestExecuteArgs(t *testing.T) {
+	a, err := ParseExecuteArgs(`2='3',0='foo',1`)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(a.Args))
+	v, ok := a.Get(0)
+	assert.True(t, ok)
+	assert.Equal(t, "foo", v)
+	_, ok = a.Get(1)
+	assert.True(t, ok)
+	assert.False(t, a.Args[1].HasValue)
+	_, ok = a.Get(5)
+	assert.False(t, ok)
+	assert.Equal(t, `0='foo',1,2='3'`, a.String())
+
+	a, err = ParseExecuteArgs("")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(a.Args))
+	assert.Equal(t, "", a.String())
+
+	a = NewExecuteArgs()
+	assert.Nil(t, a.Set(5, "http://a.b/c?d=e"))
+	assert.Nil(t, a.SetFlag(3))
+	assert.Nil(t, a.Set(3, ""))
+	assert.ErrorIs(t, a.Set(10, "a"), ErrExecuteArgsInvalidValue)
+	assert.ErrorIs(t, a.Set(1, "it's"), ErrExecuteArgsInvalidValue)
+	assert.ErrorIs(t, a.Set(1, "a b"), ErrExecuteArgsInvalidValue)
+	assert.ErrorIs(t, a.SetFlag(10), ErrExecuteArgsInvalidValue)
+	b, err := a.MarshalText()
+	assert.Nil(t, err)
+	assert.Equal(t, `3='',5='http://a.b/c?d=e'`, string(b))
+	a2 := NewExecuteArgs()
+	assert.Nil(t, a2.UnmarshalText(b))
+	assert.Equal(t, a, a2)
+}
+
+func TestExecuteArgsErrors(t *testing.T) {
+	cases := []struct {
+		s      string
+		offset int
+	}{
+		{`a`, 0},
+		{`10`, 1},
+		{`1=foo`, 2},
+		{`1='foo`, 6},
+		{`1='fo"o'`, 5},
+		{`1,`, 2},
+		{`1,1`, 2},
+		{`1 ,2`, 1},
+	}
+	for _, c := range cases {
+		_, err := ParseExecuteArgs(c.s)
+		assert.ErrorIs(t, err, ErrExecuteArgsInvalidValue, c.s)
+		var ae *ExecuteArgsError
+		if assert.ErrorAs(t, err, &ae, c.s) {
+			assert.Equal(t, c.offset, ae.Offset, c.s)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package encoding
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+var (
+	ErrLwm2mCborInvalid = errors.New("invalid lwm2m cbor data")
+)
+
+// cborTagEpochTime is the CBOR tag for epoch based date/time
+const cborTagEpochTime = 1
+
+// Lwm2mCborRecord is a leaf of a LWM2M CBOR tree with its full path from
+// root, Value is one of int64, uint64, float64, string, bool or []byte,
+// a nil Value is a CBOR null. Objlnk values are carried as text, ObjLink
+// and time.Time (encoded as tag 1) are accepted when encoding
+type Lwm2mCborRecord struct {
+	Path  []uint16
+	Value any
+}
+
+// DecodeLwm2mCbor decode a LWM2M CBOR payload (content-format 11544).
+//
+// The payload is a nested CBOR map, each key is a path component or an
+// array of path components, e.g. {3: {0: {0: "Open Mobile Alliance"}}}
+// or {[3, 0]: {0: "Open Mobile Alliance"}}, returned records are the
+// leaves of the tree
+func DecodeLwm2mCbor(data []byte) ([]*Lwm2mCborRecord, error) {
+	v, err := DecodeCbor(data)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := v.(CborMap)
+	if !ok {
+		return nil, ErrLwm2mCborInvalid
+	}
+	records := make([]*Lwm2mCborRecord, 0)
+	if err = decodeLwm2mCborMap(nil, m, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func decodeLwm2mCborMap(prefix []uint16, m CborMap, records *[]*Lwm2mCborRecord) error {
+	for _, pair := range m {
+		p := append([]uint16(nil), prefix...)
+		switch key := pair.Key.(type) {
+		case int64:
+			id, err := lwm2mCborPathId(key)
+			if err != nil {
+				return err
+			}
+			p = append(p, id)
+		case []any:
+			if len(key) == 0 {
+				return ErrLwm2mCborInvalid
+			}
+			for _, k := range key {
+				id, err := lwm2mCborPathId(k)
+				if err != nil {
+					return err
+				}
+				p = append(p, id)
+			}
+		default:
+			return ErrLwm2mCborInvalid
+		}
+		if err := decodeLwm2mCborValue(p, pair.Value, records); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func decodeLwm2mCborValue(p []uint16, v any, records *[]*Lwm2mCborRecord) error {
+	if len(p) > 4 {
+		return ErrLwm2mCborInvalid
+	}
+	switch val := v.(type) {
+	case CborMap:
+		return decodeLwm2mCborMap(p, val, records)
+	case CborTag:
+		if val.Number != cborTagEpochTime {
+			return ErrCborUnsupported
+		}
+		switch t := val.Content.(type) {
+		case int64, uint64, float64:
+			*records = append(*records, &Lwm2mCborRecord{Path: p, Value: t})
+		default:
+			return ErrLwm2mCborInvalid
+		}
+	case []any:
+		return ErrLwm2mCborInvalid
+	default:
+		*records = append(*records, &Lwm2mCborRecord{Path: p, Value: val})
+	}
+	return nil
+}
+
+func lwm2mCborPathId(v any) (uint16, error) {
+	if id, ok := v.(int64); ok && id >= 0 && id <= math.MaxUint16 {
+		return uint16(id), nil
+	}
+	return 0, ErrLwm2mCborInvalid
+}
+
+// lwm2mCborTrie is used to group records by path when encoding
+type lwm2mCborTrie struct {
+	ids      []uint16
+	children map[uint16]*lwm2mCborTrie
+	value    any
+	isLeaf   bool
+}
+
+func (t *lwm2mCborTrie) child(id uint16) *lwm2mCborTrie {
+	c, ok := t.children[id]
+	if !ok {
+		c = &lwm2mCborTrie{children: make(map[uint16]*lwm2mCborTrie)}
+		t.children[id] = c
+		t.ids = append(t.ids, id)
+	}
+	return c
+}
+
+// cbor return the CBOR map of the trie node, a chain of nodes with a
+// single child is merged into one array key
+func (t *lwm2mCborTrie) cbor() (CborMap, error) {
+	m := make(CborMap, 0, len(t.ids))
+	for _, id := range t.ids {
+		c := t.children[id]
+		key := []any{int64(id)}
+		for !c.isLeaf && len(c.ids) == 1 {
+			key = append(key, int64(c.ids[0]))
+			c = c.children[c.ids[0]]
+		}
+		var val any
+		if c.isLeaf {
+			if len(c.ids) > 0 {
+				return nil, ErrLwm2mCborInvalid
+			}
+			val = c.value
+		} else {
+			cm, err := c.cbor()
+			if err != nil {
+				return nil, err
+			}
+			val = cm
+		}
+		if len(key) == 1 {
+			m = append(m, CborPair{Key: key[0], Value: val})
+		} else {
+			m = append(m, CborPair{Key: key, Value: val})
+		}
+	}
+	return m, nil
+}
+
+// EncodeLwm2mCbor encode records to a LWM2M CBOR payload, path components
+// shared by a single branch are merged to array keys
+func EncodeLwm2mCbor(records []*Lwm2mCborRecord) ([]byte, error) {
+	root := &lwm2mCborTrie{children: make(map[uint16]*lwm2mCborTrie)}
+	for _, r := range records {
+		if len(r.Path) == 0 || len(r.Path) > 4 {
+			return nil, ErrLwm2mCborInvalid
+		}
+		n := root
+		for _, id := range r.Path {
+			n = n.child(id)
+		}
+		if n.isLeaf {
+			return nil, ErrLwm2mCborInvalid
+		}
+		n.isLeaf = true
+		switch v := r.Value.(type) {
+		case nil, int64, uint64, float64, string, bool, []byte:
+			n.value = v
+		case ObjLink:
+			n.value = v.String()
+		case time.Time:
+			n.value = CborTag{Number: cborTagEpochTime, Content: v.Unix()}
+		default:
+			return nil, ErrUnknownType
+		}
+	}
+	m, err := root.cbor()
+	if err != nil {
+		return nil, err
+	}
+	return EncodeCbor(m)
+}
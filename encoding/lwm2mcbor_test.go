@@ -0,0 +1,72 @@
+package encoding
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeLwm2mCbor(t *testing.T) {
+	// {[3, 0]: {0: "Open", 6: {0: 1, 1: 5}, 13: 1(1367491215), 9: 2.5}, 3303: {0: {5700: -5}}}
+	str := `
+A2
+82 03 00
+A4
+00 64 4F 70 65 6E
+06 A2 00 01 01 05
+0D C1 1A 51 82 42 8F
+09 FA 40 20 00 00
+19 0C E7 A1 00 A1 19 16 44 24`
+	data, err := _strToByte(str)
+	assert.Nil(t, err)
+	records, err := DecodeLwm2mCbor(data)
+	assert.Nil(t, err)
+	assert.Equal(t, []*Lwm2mCborRecord{
+		{Path: []uint16{3, 0, 0}, Value: "Open"},
+		{Path: []uint16{3, 0, 6, 0}, Value: int64(1)},
+		{Path: []uint16{3, 0, 6, 1}, Value: int64(5)},
+		{Path: []uint16{3, 0, 13}, Value: int64(1367491215)},
+		{Path: []uint16{3, 0, 9}, Value: 2.5},
+		{Path: []uint16{3303, 0, 5700}, Value: int64(-5)},
+	}, records)
+
+	// root must be a map
+	_, err = DecodeLwm2mCbor([]byte{0x80})
+	assert.Equal(t, ErrLwm2mCborInvalid, err)
+	// path too deep {[1, 2, 3, 4, 5]: 1}
+	_, err = DecodeLwm2mCbor([]byte{0xA1, 0x85, 0x01, 0x02, 0x03, 0x04, 0x05, 0x01})
+	assert.Equal(t, ErrLwm2mCborInvalid, err)
+	// negative path id {-1: 1}
+	_, err = DecodeLwm2mCbor([]byte{0xA1, 0x20, 0x01})
+	assert.Equal(t, ErrLwm2mCborInvalid, err)
+}
+
+func TestEncodeLwm2mCbor(t *testing.T) {
+	records := []*Lwm2mCborRecord{
+		{Path: []uint16{3, 0, 0}, Value: "Open"},
+		{Path: []uint16{3, 0, 6, 0}, Value: int64(1)},
+		{Path: []uint16{3, 0, 6, 1}, Value: int64(5)},
+		{Path: []uint16{3, 0, 11, 0}, Value: int64(0)},
+	}
+	data, err := EncodeLwm2mCbor(records)
+	assert.Nil(t, err)
+	// {[3, 0]: {0: "Open", 6: {0: 1, 1: 5}, [11, 0]: 0}}
+	expected, _ := _strToByte(`A1 82 03 00 A3 00 64 4F 70 65 6E 06 A2 00 01 01 05 82 0B 00 00`)
+	assert.Equal(t, expected, data)
+	decoded, err := DecodeLwm2mCbor(data)
+	assert.Nil(t, err)
+	assert.Equal(t, records, decoded)
+
+	data, err = EncodeLwm2mCbor([]*Lwm2mCborRecord{
+		{Path: []uint16{1, 0, 1}, Value: ObjLink{ObjectId: 3, InstanceId: 0}},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0xA1, 0x83, 0x01, 0x00, 0x01, 0x63, 0x33, 0x3A, 0x30}, data)
+
+	// resource with both a value and instances
+	_, err = EncodeLwm2mCbor([]*Lwm2mCborRecord{
+		{Path: []uint16{3, 0, 6}, Value: int64(1)},
+		{Path: []uint16{3, 0, 6, 0}, Value: int64(1)},
+	})
+	assert.Equal(t, ErrLwm2mCborInvalid, err)
+}
@@ -0,0 +1,153 @@
+package encoding
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrLwm2mJSONInvalid = errors.New("invalid lwm2m json data")
+)
+
+// lwm2mJSON is the LWM2M 1.0 JSON format (content-format 11543)
+//
+//	{"bn":"/3/0/",
+//	 "e":[{"n":"0","sv":"Open Mobile Alliance"},
+//	      {"n":"6/0","v":1},
+//	      {"n":"13","v":1367491215}]}
+//
+// Opaque values are carried as base64 encoded "sv"
+type lwm2mJSON struct {
+	BaseName string            `json:"bn,omitempty"`
+	BaseTime float64           `json:"bt,omitempty"`
+	Entries  []*lwm2mJSONEntry `json:"e"`
+}
+
+type lwm2mJSONEntry struct {
+	Name        string       `json:"n,omitempty"`
+	Time        float64      `json:"t,omitempty"`
+	Value       *json.Number `json:"v,omitempty"`
+	StringValue *string      `json:"sv,omitempty"`
+	BoolValue   *bool        `json:"bv,omitempty"`
+	ObjLnkValue *string      `json:"ov,omitempty"`
+}
+
+// DecodeLwm2mJSON decode a LWM2M JSON payload to SenML records, base
+// name and base time are set to the first record
+func DecodeLwm2mJSON(data []byte) ([]*SenMLRecord, error) {
+	var pack lwm2mJSON
+	if err := json.Unmarshal(data, &pack); err != nil {
+		return nil, err
+	}
+	records := make([]*SenMLRecord, 0, len(pack.Entries))
+	for i, e := range pack.Entries {
+		if e == nil {
+			return nil, ErrLwm2mJSONInvalid
+		}
+		r := &SenMLRecord{
+			Name: e.Name,
+			Time: e.Time,
+		}
+		if i == 0 {
+			r.BaseName = pack.BaseName
+			r.BaseTime = pack.BaseTime
+		}
+		count := 0
+		if e.Value != nil {
+			v, err := parseSenMLNumber(e.Value.String())
+			if err != nil {
+				return nil, err
+			}
+			r.Value = v
+			count++
+		}
+		if e.StringValue != nil {
+			r.Value = *e.StringValue
+			count++
+		}
+		if e.BoolValue != nil {
+			r.Value = *e.BoolValue
+			count++
+		}
+		if e.ObjLnkValue != nil {
+			v, err := ParseObjLink(*e.ObjLnkValue)
+			if err != nil {
+				return nil, err
+			}
+			r.Value = v
+			count++
+		}
+		if count > 1 {
+			return nil, ErrLwm2mJSONInvalid
+		}
+		records = append(records, r)
+	}
+	return records, nil
+}
+
+// EncodeLwm2mJSON encode SenML records to a LWM2M JSON payload, the common
+// path prefix of the records is used as base name
+func EncodeLwm2mJSON(records []*SenMLRecord) ([]byte, error) {
+	resolved := ResolveSenML(records)
+	pack := lwm2mJSON{
+		BaseName: lwm2mJSONBaseName(resolved),
+		Entries:  make([]*lwm2mJSONEntry, 0, len(resolved)),
+	}
+	for _, r := range resolved {
+		e := &lwm2mJSONEntry{
+			Name: strings.TrimPrefix(r.Name, pack.BaseName),
+			Time: r.Time,
+		}
+		switch v := senmlValue(r.Value).(type) {
+		case nil:
+		case float64:
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				return nil, ErrLwm2mJSONInvalid
+			}
+			n := json.Number(strconv.FormatFloat(v, 'g', -1, 64))
+			e.Value = &n
+		case int64:
+			n := json.Number(strconv.FormatInt(v, 10))
+			e.Value = &n
+		case uint64:
+			n := json.Number(strconv.FormatUint(v, 10))
+			e.Value = &n
+		case string:
+			e.StringValue = &v
+		case bool:
+			e.BoolValue = &v
+		case []byte:
+			sv := base64.StdEncoding.EncodeToString(v)
+			e.StringValue = &sv
+		case ObjLink:
+			ov := v.String()
+			e.ObjLnkValue = &ov
+		default:
+			return nil, ErrUnknownType
+		}
+		pack.Entries = append(pack.Entries, e)
+	}
+	return json.Marshal(pack)
+}
+
+// lwm2mJSONBaseName return the longest common prefix of names that ends
+// with "/"
+func lwm2mJSONBaseName(records []*SenMLRecord) string {
+	if len(records) == 0 {
+		return ""
+	}
+	prefix := records[0].Name
+	for _, r := range records[1:] {
+		for !strings.HasPrefix(r.Name, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		return prefix[:i+1]
+	}
+	return ""
+}
@@ -0,0 +1,65 @@
+package encoding
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeLwm2mJSON(t *testing.T) {
+	data := `{"bn":"/3/0/","e":[
+{"n":"0","sv":"Open Mobile Alliance"},
+{"n":"6/0","v":1},
+{"n":"9","v":95.5},
+{"n":"10","bv":true},
+{"n":"11","ov":"3:1"},
+{"n":"12","sv":"AQIDBA=="}]}`
+	records, err := DecodeLwm2mJSON([]byte(data))
+	assert.Nil(t, err)
+	assert.Equal(t, 6, len(records))
+	resolved := ResolveSenML(records)
+	assert.Equal(t, "/3/0/0", resolved[0].Name)
+	assert.Equal(t, "Open Mobile Alliance", resolved[0].Value)
+	assert.Equal(t, "/3/0/6/0", resolved[1].Name)
+	assert.Equal(t, int64(1), resolved[1].Value)
+	assert.Equal(t, 95.5, resolved[2].Value)
+	assert.Equal(t, true, resolved[3].Value)
+	assert.Equal(t, ObjLink{ObjectId: 3, InstanceId: 1}, resolved[4].Value)
+	tv := &TypedValue{Value: resolved[5].Value, Base64: true}
+	assert.Equal(t, []byte{0x01, 0x02, 0x03, 0x04}, tv.Opaque())
+	// strings of other formats are not base64
+	tv = &TypedValue{Value: resolved[5].Value}
+	assert.Equal(t, []byte("AQIDBA=="), tv.Opaque())
+
+	_, err = DecodeLwm2mJSON([]byte(`{"e":[{"n":"1","v":1,"bv":false}]}`))
+	assert.Equal(t, ErrLwm2mJSONInvalid, err)
+	_, err = DecodeLwm2mJSON([]byte(`{"e":[{"n":"1","ov":"3"}]}`))
+	assert.NotNil(t, err)
+}
+
+func TestEncodeLwm2mJSON(t *testing.T) {
+	records := []*SenMLRecord{
+		{Name: "/3/0/0", Value: "Open Mobile Alliance"},
+		{Name: "/3/0/6/0", Value: int64(1)},
+		{Name: "/3/0/9", Value: 95.5},
+		{Name: "/3/0/10", Value: false},
+		{Name: "/3/0/11", Value: ObjLink{ObjectId: 3, InstanceId: 1}},
+		{Name: "/3/0/12", Value: []byte{0x01, 0x02, 0x03, 0x04}},
+	}
+	data, err := EncodeLwm2mJSON(records)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"bn":"/3/0/","e":[{"n":"0","sv":"Open Mobile Alliance"},`+
+		`{"n":"6/0","v":1},{"n":"9","v":95.5},{"n":"10","bv":false},`+
+		`{"n":"11","ov":"3:1"},{"n":"12","sv":"AQIDBA=="}]}`, string(data))
+
+	data, err = EncodeLwm2mJSON([]*SenMLRecord{{Name: "/3/0/9", Value: int64(1)}})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"bn":"/3/0/","e":[{"n":"9","v":1}]}`, string(data))
+
+	data, err = EncodeLwm2mJSON([]*SenMLRecord{
+		{Name: "/3/0/9", Value: int64(1)},
+		{Name: "/4/0/2", Value: int64(2)},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, `{"bn":"/","e":[{"n":"3/0/9","v":1},{"n":"4/0/2","v":2}]}`, string(data))
+}
@@ -42,6 +42,10 @@ func (o *OpaqueValue) Integer() (val int64, err error) {
 	return
 }
 
+func (o *OpaqueValue) Unsigned() (val uint64, err error) {
+	return binaryUnsigned(o.Value)
+}
+
 func (o *OpaqueValue) Float() (float64, error) {
 	if len(o.Value) == 4 {
 		return float64(math.Float32frombits(binary.BigEndian.Uint32(o.Value))), nil
@@ -92,12 +96,10 @@ func (o *OpaqueValue) Raw() []byte {
 func NewOpaqueValue(v any) (*OpaqueValue, error) {
 	var val any
 	switch v.(type) {
-	case int:
-		val = int64(v.(int))
 	case string:
 		val = []byte(v.(string))
 	default:
-		val = v
+		val = binaryValue(v)
 	}
 	buf := new(bytes.Buffer)
 	err := binary.Write(buf, binary.BigEndian, val)
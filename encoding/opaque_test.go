@@ -2,6 +2,7 @@ package encoding
 
 import (
 	"github.com/stretchr/testify/assert"
+	"math"
 	"testing"
 )
 
@@ -54,4 +55,15 @@ func TestOpaque(t *testing.T) {
 		assert.Nil(t, err)
 		assert.Equal(t, []byte{0x01, 0x02, 0x03, 0x04}, val.Raw())
 	}
+	{ // unsigned above 2^63
+		val, err := NewOpaqueValue(uint64(math.MaxUint64))
+		assert.Nil(t, err)
+		assert.Equal(t, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, val.Raw())
+		u, err := val.Unsigned()
+		assert.Nil(t, err)
+		assert.Equal(t, uint64(math.MaxUint64), u)
+		u, err = (&OpaqueValue{Value: []byte{0xff, 0xfe}}).Unsigned()
+		assert.Nil(t, err)
+		assert.Equal(t, uint64(0xfffe), u)
+	}
 }
@@ -0,0 +1,82 @@
+package encoding
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+var (
+	ErrSenMLInvalidRecord = errors.New("invalid senml record")
+)
+
+// SenMLRecord is one record of a SenML pack, defines in RFC8428,
+// LWM2M 1.1 use SenML JSON and SenML CBOR to carry multiple resources.
+//
+// Value hold the record value with the Go type that select the SenML field:
+//
+//	float64, int64, uint64 -> "v"
+//	string                 -> "vs"
+//	bool                   -> "vb"
+//	[]byte                 -> "vd"
+//	ObjLink                -> "vlo"
+//
+// time.Time is accepted when encoding and carried as "v" in seconds.
+// A nil Value means the record has no value, e.g. path only records
+// in a Read-Composite request.
+type SenMLRecord struct {
+	BaseName string
+	BaseTime float64
+	Name     string
+	Time     float64
+	Value    any
+}
+
+// ResolveSenML applies the base fields of a SenML pack to its records,
+// returned records have full Name and Time, and empty base fields
+func ResolveSenML(records []*SenMLRecord) []*SenMLRecord {
+	resolved := make([]*SenMLRecord, 0, len(records))
+	baseName := ""
+	var baseTime float64 = 0
+	for _, r := range records {
+		if r.BaseName != "" {
+			baseName = r.BaseName
+		}
+		if r.BaseTime != 0 {
+			baseTime = r.BaseTime
+		}
+		resolved = append(resolved, &SenMLRecord{
+			Name:  baseName + r.Name,
+			Time:  baseTime + r.Time,
+			Value: r.Value,
+		})
+	}
+	return resolved
+}
+
+// senmlRelativeTime is the limit below which SenML times are relative to
+// the current time, RFC8428 4.5.3
+const senmlRelativeTime = 1 << 28
+
+// SenMLTime convert the time t of a resolved SenML record to an absolute
+// time, times below 2**28 are seconds relative to now. A zero time means
+// the record has no time and false is returned
+func SenMLTime(t float64, now time.Time) (time.Time, bool) {
+	if t == 0 {
+		return time.Time{}, false
+	}
+	if t < senmlRelativeTime {
+		return now.Add(time.Duration(t * float64(time.Second))), true
+	}
+	sec, frac := math.Modf(t)
+	return time.Unix(int64(sec), int64(frac*1e9)), true
+}
+
+// senmlValue convert values without a SenML field to the one they are
+// carried with
+func senmlValue(v any) any {
+	if t, ok := v.(time.Time); ok {
+		return t.Unix()
+	}
+	return v
+}
@@ -0,0 +1,152 @@
+package encoding
+
+import "math"
+
+// SenML CBOR labels, defines in RFC8428 Table 4, LWM2M use the text
+// label "vlo" for Objlnk values
+const (
+	senmlLabelBaseName    = -2
+	senmlLabelBaseTime    = -3
+	senmlLabelName        = 0
+	senmlLabelValue       = 2
+	senmlLabelStringValue = 3
+	senmlLabelBoolValue   = 4
+	senmlLabelTime        = 6
+	senmlLabelDataValue   = 8
+	senmlLabelObjLnkValue = "vlo"
+)
+
+// DecodeSenMLCbor decode a SenML CBOR pack (content-format 112),
+// base fields are kept as is, use ResolveSenML to get full names
+func DecodeSenMLCbor(data []byte) ([]*SenMLRecord, error) {
+	v, err := DecodeCbor(data)
+	if err != nil {
+		return nil, err
+	}
+	pack, ok := v.([]any)
+	if !ok {
+		return nil, ErrSenMLInvalidRecord
+	}
+	records := make([]*SenMLRecord, 0, len(pack))
+	for _, item := range pack {
+		m, ok := item.(CborMap)
+		if !ok {
+			return nil, ErrSenMLInvalidRecord
+		}
+		r, err := senmlRecordFromCbor(m)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+	return records, nil
+}
+
+func senmlRecordFromCbor(m CborMap) (*SenMLRecord, error) {
+	r := &SenMLRecord{}
+	count := 0
+	for _, pair := range m {
+		k, v := pair.Key, pair.Value
+		var ok bool
+		switch k {
+		case int64(senmlLabelBaseName):
+			r.BaseName, ok = v.(string)
+		case int64(senmlLabelBaseTime):
+			r.BaseTime, ok = cborNumberToFloat(v)
+		case int64(senmlLabelName):
+			r.Name, ok = v.(string)
+		case int64(senmlLabelTime):
+			r.Time, ok = cborNumberToFloat(v)
+		case int64(senmlLabelValue):
+			_, ok = cborNumberToFloat(v)
+			r.Value = v
+			count++
+		case int64(senmlLabelStringValue):
+			r.Value, ok = v.(string)
+			count++
+		case int64(senmlLabelBoolValue):
+			r.Value, ok = v.(bool)
+			count++
+		case int64(senmlLabelDataValue):
+			r.Value, ok = v.([]byte)
+			count++
+		case senmlLabelObjLnkValue:
+			var s string
+			if s, ok = v.(string); ok {
+				var err error
+				if r.Value, err = ParseObjLink(s); err != nil {
+					return nil, err
+				}
+			}
+			count++
+		default:
+			// unknown labels such as units are ignored
+			ok = true
+		}
+		if !ok {
+			return nil, ErrSenMLInvalidRecord
+		}
+	}
+	if count > 1 {
+		return nil, ErrSenMLInvalidRecord
+	}
+	return r, nil
+}
+
+func cborNumberToFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// senmlCborTime encode integral times as integers, which are shorter
+// than a double precision float
+func senmlCborTime(t float64) any {
+	if t == math.Trunc(t) && math.Abs(t) < 1<<53 {
+		return int64(t)
+	}
+	return t
+}
+
+// EncodeSenMLCbor encode records to a SenML CBOR pack
+func EncodeSenMLCbor(records []*SenMLRecord) ([]byte, error) {
+	pack := make([]any, 0, len(records))
+	for _, r := range records {
+		m := make(map[any]any)
+		if r.BaseName != "" {
+			m[senmlLabelBaseName] = r.BaseName
+		}
+		if r.BaseTime != 0 {
+			m[senmlLabelBaseTime] = senmlCborTime(r.BaseTime)
+		}
+		if r.Name != "" {
+			m[senmlLabelName] = r.Name
+		}
+		if r.Time != 0 {
+			m[senmlLabelTime] = senmlCborTime(r.Time)
+		}
+		switch v := senmlValue(r.Value).(type) {
+		case nil:
+		case float64, int64, uint64:
+			m[senmlLabelValue] = v
+		case string:
+			m[senmlLabelStringValue] = v
+		case bool:
+			m[senmlLabelBoolValue] = v
+		case []byte:
+			m[senmlLabelDataValue] = v
+		case ObjLink:
+			m[senmlLabelObjLnkValue] = v.String()
+		default:
+			return nil, ErrUnknownType
+		}
+		pack = append(pack, m)
+	}
+	return EncodeCbor(pack)
+}
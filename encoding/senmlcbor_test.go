@@ -0,0 +1,68 @@
+package encoding
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeSenMLCbor(t *testing.T) {
+	// [{-2: "/3/0/", 0: "0", 3: "Open Mobile Alliance"}, {0: "9", 2: 100},
+	//  {0: "10", 2: 2.5}, {0: "11", 4: true}, {0: "12", 8: h'01020304'},
+	//  {0: "13", "vlo": "3:0"}, {0: "14", 6: 10, 2: -5}]
+	str := `
+87
+A3 21 65 2F 33 2F 30 2F 00 61 30 03 74 4F 70 65 6E 20 4D 6F 62 69 6C 65 20 41 6C 6C 69 61 6E 63 65
+A2 00 61 39 02 18 64
+A2 00 62 31 30 02 FA 40 20 00 00
+A2 00 62 31 31 04 F5
+A2 00 62 31 32 08 44 01 02 03 04
+A2 00 62 31 33 63 76 6C 6F 63 33 3A 30
+A3 00 62 31 34 06 0A 02 24`
+	data, err := _strToByte(str)
+	assert.Nil(t, err)
+	records, err := DecodeSenMLCbor(data)
+	assert.Nil(t, err)
+	assert.Equal(t, 7, len(records))
+	assert.Equal(t, "/3/0/", records[0].BaseName)
+	assert.Equal(t, "Open Mobile Alliance", records[0].Value)
+	assert.Equal(t, int64(100), records[1].Value)
+	assert.Equal(t, 2.5, records[2].Value)
+	assert.Equal(t, true, records[3].Value)
+	assert.Equal(t, []byte{0x01, 0x02, 0x03, 0x04}, records[4].Value)
+	assert.Equal(t, ObjLink{ObjectId: 3, InstanceId: 0}, records[5].Value)
+	assert.Equal(t, int64(-5), records[6].Value)
+	assert.Equal(t, float64(10), records[6].Time)
+
+	resolved := ResolveSenML(records)
+	assert.Equal(t, "/3/0/14", resolved[6].Name)
+
+	// value with wrong type
+	_, err = DecodeSenMLCbor([]byte{0x81, 0xA1, 0x03, 0x01})
+	assert.Equal(t, ErrSenMLInvalidRecord, err)
+	// not an array
+	_, err = DecodeSenMLCbor([]byte{0xA0})
+	assert.Equal(t, ErrSenMLInvalidRecord, err)
+}
+
+func TestEncodeSenMLCbor(t *testing.T) {
+	records := []*SenMLRecord{
+		{BaseName: "/3/0/", Name: "0", Value: "Open Mobile Alliance"},
+		{Name: "9", Value: int64(100)},
+		{Name: "10", Value: 2.5},
+		{Name: "11", Value: false},
+		{Name: "12", Value: []byte{0x01, 0x02, 0x03, 0x04}},
+		{Name: "13", Value: ObjLink{ObjectId: 1, InstanceId: 2}},
+		{Name: "14", Time: 1367491215, Value: uint64(18446744073709551615)},
+		{Name: "15"},
+	}
+	data, err := EncodeSenMLCbor(records)
+	assert.Nil(t, err)
+	decoded, err := DecodeSenMLCbor(data)
+	assert.Nil(t, err)
+	assert.Equal(t, records, decoded)
+
+	data, err = EncodeSenMLCbor([]*SenMLRecord{{Name: "9", Value: int64(100)}})
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0x81, 0xA2, 0x00, 0x61, 0x39, 0x02, 0x18, 0x64}, data)
+}
@@ -0,0 +1,164 @@
+package encoding
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// senmlJSONRecord is the JSON representation of a SenMLRecord,
+// numeric value is kept as json.Number so that integers are not
+// truncated by a float64 round trip
+type senmlJSONRecord struct {
+	BaseName    string       `json:"bn,omitempty"`
+	BaseTime    float64      `json:"bt,omitempty"`
+	Name        string       `json:"n,omitempty"`
+	Time        float64      `json:"t,omitempty"`
+	Value       *json.Number `json:"v,omitempty"`
+	StringValue *string      `json:"vs,omitempty"`
+	BoolValue   *bool        `json:"vb,omitempty"`
+	DataValue   *string      `json:"vd,omitempty"`
+	ObjLnkValue *string      `json:"vlo,omitempty"`
+}
+
+func (j *senmlJSONRecord) toRecord() (*SenMLRecord, error) {
+	r := &SenMLRecord{
+		BaseName: j.BaseName,
+		BaseTime: j.BaseTime,
+		Name:     j.Name,
+		Time:     j.Time,
+	}
+	count := 0
+	if j.Value != nil {
+		v, err := parseSenMLNumber(j.Value.String())
+		if err != nil {
+			return nil, err
+		}
+		r.Value = v
+		count++
+	}
+	if j.StringValue != nil {
+		r.Value = *j.StringValue
+		count++
+	}
+	if j.BoolValue != nil {
+		r.Value = *j.BoolValue
+		count++
+	}
+	if j.DataValue != nil {
+		v, err := decodeSenMLData(*j.DataValue)
+		if err != nil {
+			return nil, err
+		}
+		r.Value = v
+		count++
+	}
+	if j.ObjLnkValue != nil {
+		v, err := ParseObjLink(*j.ObjLnkValue)
+		if err != nil {
+			return nil, err
+		}
+		r.Value = v
+		count++
+	}
+	if count > 1 {
+		return nil, ErrSenMLInvalidRecord
+	}
+	return r, nil
+}
+
+func newSenMLJSONRecord(r *SenMLRecord) (*senmlJSONRecord, error) {
+	j := &senmlJSONRecord{
+		BaseName: r.BaseName,
+		BaseTime: r.BaseTime,
+		Name:     r.Name,
+		Time:     r.Time,
+	}
+	switch v := senmlValue(r.Value).(type) {
+	case nil:
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, ErrSenMLInvalidRecord
+		}
+		n := json.Number(strconv.FormatFloat(v, 'g', -1, 64))
+		j.Value = &n
+	case int64:
+		n := json.Number(strconv.FormatInt(v, 10))
+		j.Value = &n
+	case uint64:
+		n := json.Number(strconv.FormatUint(v, 10))
+		j.Value = &n
+	case string:
+		j.StringValue = &v
+	case bool:
+		j.BoolValue = &v
+	case []byte:
+		d := base64.RawURLEncoding.EncodeToString(v)
+		j.DataValue = &d
+	case ObjLink:
+		l := v.String()
+		j.ObjLnkValue = &l
+	default:
+		return nil, ErrUnknownType
+	}
+	return j, nil
+}
+
+// parseSenMLNumber keep integers as int64 or uint64 when possible
+func parseSenMLNumber(s string) (any, error) {
+	if !strings.ContainsAny(s, ".eE") {
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i, nil
+		}
+		if u, err := strconv.ParseUint(s, 10, 64); err == nil {
+			return u, nil
+		}
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// decodeSenMLData decode "vd" value, RFC8428 use base64url,
+// padding is optional, standard base64 is accepted too
+func decodeSenMLData(s string) ([]byte, error) {
+	s = strings.TrimRight(s, "=")
+	if strings.ContainsAny(s, "+/") {
+		return base64.RawStdEncoding.DecodeString(s)
+	}
+	return base64.RawURLEncoding.DecodeString(s)
+}
+
+// DecodeSenMLJSON decode a SenML JSON pack (content-format 110),
+// base fields are kept as is, use ResolveSenML to get full names
+func DecodeSenMLJSON(data []byte) ([]*SenMLRecord, error) {
+	var pack []*senmlJSONRecord
+	if err := json.Unmarshal(data, &pack); err != nil {
+		return nil, err
+	}
+	records := make([]*SenMLRecord, 0, len(pack))
+	for _, j := range pack {
+		if j == nil {
+			return nil, ErrSenMLInvalidRecord
+		}
+		r, err := j.toRecord()
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+	return records, nil
+}
+
+// EncodeSenMLJSON encode records to a SenML JSON pack
+func EncodeSenMLJSON(records []*SenMLRecord) ([]byte, error) {
+	pack := make([]*senmlJSONRecord, 0, len(records))
+	for _, r := range records {
+		j, err := newSenMLJSONRecord(r)
+		if err != nil {
+			return nil, err
+		}
+		pack = append(pack, j)
+	}
+	return json.Marshal(pack)
+}
@@ -0,0 +1,88 @@
+package encoding
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeSenMLJSON(t *testing.T) {
+	data := `[{"bn":"/3/0/","n":"0","vs":"Open Mobile Alliance"},
+{"n":"6/0","v":1},
+{"n":"7/0","v":3800},
+{"n":"9","v":100.5},
+{"n":"13","v":18446744073709551615},
+{"n":"14","vb":true},
+{"n":"15","vd":"AQIDBA"},
+{"n":"16","vlo":"3:0"}]`
+	records, err := DecodeSenMLJSON([]byte(data))
+	assert.Nil(t, err)
+	assert.Equal(t, 8, len(records))
+	assert.Equal(t, "/3/0/", records[0].BaseName)
+	assert.Equal(t, "Open Mobile Alliance", records[0].Value)
+	assert.Equal(t, int64(1), records[1].Value)
+	assert.Equal(t, 100.5, records[3].Value)
+	assert.Equal(t, uint64(18446744073709551615), records[4].Value)
+	assert.Equal(t, true, records[5].Value)
+	assert.Equal(t, []byte{0x01, 0x02, 0x03, 0x04}, records[6].Value)
+	assert.Equal(t, ObjLink{ObjectId: 3, InstanceId: 0}, records[7].Value)
+
+	resolved := ResolveSenML(records)
+	assert.Equal(t, "/3/0/0", resolved[0].Name)
+	assert.Equal(t, "/3/0/6/0", resolved[1].Name)
+	assert.Equal(t, "/3/0/16", resolved[7].Name)
+
+	_, err = DecodeSenMLJSON([]byte(`[{"n":"/3/0/1","v":1,"vs":"1"}]`))
+	assert.NotNil(t, err)
+	_, err = DecodeSenMLJSON([]byte(`{"n":"/3/0/1"}`))
+	assert.NotNil(t, err)
+}
+
+func TestEncodeSenMLJSON(t *testing.T) {
+	records := []*SenMLRecord{
+		{BaseName: "/3/0/", Name: "0", Value: "Open Mobile Alliance"},
+		{Name: "9", Value: int64(100)},
+		{Name: "10", Value: 2.5},
+		{Name: "11", Value: false},
+		{Name: "12", Value: []byte{0x01, 0x02, 0x03, 0x04}},
+		{Name: "13", Value: ObjLink{ObjectId: 1, InstanceId: 2}},
+		{Name: "14"},
+	}
+	data, err := EncodeSenMLJSON(records)
+	assert.Nil(t, err)
+	assert.Equal(t, `[{"bn":"/3/0/","n":"0","vs":"Open Mobile Alliance"},`+
+		`{"n":"9","v":100},{"n":"10","v":2.5},{"n":"11","vb":false},`+
+		`{"n":"12","vd":"AQIDBA"},{"n":"13","vlo":"1:2"},{"n":"14"}]`, string(data))
+
+	decoded, err := DecodeSenMLJSON(data)
+	assert.Nil(t, err)
+	assert.Equal(t, records, decoded)
+
+	_, err = EncodeSenMLJSON([]*SenMLRecord{{Name: "/3/0/1", Value: int(1)}})
+	assert.NotNil(t, err)
+}
+
+func TestSenMLTime(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	_, ok := SenMLTime(0, now)
+	assert.False(t, ok)
+	ts, ok := SenMLTime(-60, now)
+	assert.True(t, ok)
+	assert.Equal(t, now.Add(-time.Minute), ts)
+	ts, ok = SenMLTime(1367491215.5, now)
+	assert.True(t, ok)
+	assert.Equal(t, time.Unix(1367491215, 500000000), ts)
+
+	records := ResolveSenML([]*SenMLRecord{
+		{BaseName: "/3303/0/5700", BaseTime: 1700000000, Time: -20, Value: 21.0},
+		{Time: -10, Value: 21.5},
+		{Value: 22.0},
+	})
+	ts, ok = SenMLTime(records[1].Time, now)
+	assert.True(t, ok)
+	assert.Equal(t, time.Unix(1699999990, 0), ts)
+	ts, ok = SenMLTime(records[2].Time, now)
+	assert.True(t, ok)
+	assert.Equal(t, time.Unix(1700000000, 0), ts)
+}
@@ -2,10 +2,9 @@ package encoding
 
 import (
 	"encoding/base64"
-	"errors"
 	"fmt"
 	"strconv"
-	"strings"
+	"time"
 )
 
 type PlainTextValue struct {
@@ -20,6 +19,10 @@ func (p *PlainTextValue) Integer() (val int64, err error) {
 	return strconv.ParseInt(string(p.Value), 10, 64)
 }
 
+func (p *PlainTextValue) Unsigned() (val uint64, err error) {
+	return strconv.ParseUint(string(p.Value), 10, 64)
+}
+
 func (p *PlainTextValue) Float() (float64, error) {
 	return strconv.ParseFloat(string(p.Value), 64)
 }
@@ -49,19 +52,11 @@ func (p *PlainTextValue) Time() (int64, error) {
 }
 
 func (p *PlainTextValue) ObjectLink() (uint16, uint16, error) {
-	splitted := strings.Split(string(p.Value), ":")
-	if len(splitted) != 2 {
-		return 0, 0, errors.New("wrong ObjLink text representation")
-	}
-	od, err := strconv.ParseUint(splitted[0], 10, 16)
-	if err != nil {
-		return 0, 0, err
-	}
-	oid, err := strconv.ParseUint(splitted[1], 10, 16)
+	l, err := ParseObjLink(string(p.Value))
 	if err != nil {
 		return 0, 0, err
 	}
-	return uint16(od), uint16(oid), nil
+	return l.ObjectId, l.InstanceId, nil
 }
 
 func (p *PlainTextValue) Raw() []byte {
@@ -117,13 +112,15 @@ func NewPlainTextValue(val any) (*PlainTextValue, error) {
 	case int64:
 		err = pt.fromInteger(val.(int64))
 	case uint:
-		err = pt.fromInteger(int64(val.(uint)))
+		err = pt.fromString(strconv.FormatUint(uint64(val.(uint)), 10))
 	case uint8:
 		err = pt.fromInteger(int64(val.(uint8)))
 	case uint16:
 		err = pt.fromInteger(int64(val.(uint16)))
 	case uint32:
 		err = pt.fromInteger(int64(val.(uint32)))
+	case uint64:
+		err = pt.fromString(strconv.FormatUint(val.(uint64), 10))
 	case float32:
 		err = pt.fromFloat(float64(val.(float32)))
 	case float64:
@@ -134,6 +131,10 @@ func NewPlainTextValue(val any) (*PlainTextValue, error) {
 		err = pt.fromOpaque(val.([]byte))
 	case bool:
 		err = pt.fromBool(val.(bool))
+	case time.Time:
+		err = pt.fromTime(val.(time.Time).Unix())
+	case ObjLink:
+		err = pt.fromString(val.(ObjLink).String())
 	default:
 		err = ErrUnknownType
 	}
@@ -2,6 +2,7 @@ package encoding
 
 import (
 	"github.com/stretchr/testify/assert"
+	"math"
 	"testing"
 )
 
@@ -39,4 +40,14 @@ func TestPlainText(t *testing.T) {
 		assert.Nil(t, err)
 		assert.Equal(t, []byte("AQIDBA=="), val.Raw())
 	}
+	{ // unsigned above 2^63
+		val, err := NewPlainTextValue(uint64(math.MaxUint64))
+		assert.Nil(t, err)
+		assert.Equal(t, []byte("18446744073709551615"), val.Raw())
+		u, err := val.Unsigned()
+		assert.Nil(t, err)
+		assert.Equal(t, uint64(math.MaxUint64), u)
+		_, err = val.Integer()
+		assert.NotNil(t, err)
+	}
 }
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"math"
+	"time"
 )
 
 type TlvType byte
@@ -192,6 +193,10 @@ func (t *Tlv) Integer() (val int64, err error) {
 	return
 }
 
+func (t *Tlv) Unsigned() (val uint64, err error) {
+	return binaryUnsigned(t.Value)
+}
+
 func (t *Tlv) Float() (float64, error) {
 	if t.Length == 4 {
 		return float64(math.Float32frombits(binary.BigEndian.Uint32(t.Value))), nil
@@ -275,16 +280,19 @@ func EncodeTlv(tlvs []*Tlv) []byte {
 	return data
 }
 
+// NewTlv create a Tlv with the binary representation of v, int and uint
+// are encoded as 64 bits, time.Time as seconds since epoch and ObjLink as
+// two 16 bits ids
 func NewTlv(t TlvType, id uint16, v any) *Tlv {
 	var value []byte
-	switch v.(type) {
+	switch vv := v.(type) {
 	case string:
-		value = []byte(v.(string))
+		value = []byte(vv)
 	case *string:
-		value = []byte(*v.(*string))
+		value = []byte(*vv)
 	default:
 		buf := new(bytes.Buffer)
-		err := binary.Write(buf, binary.BigEndian, v)
+		err := binary.Write(buf, binary.BigEndian, binaryValue(v))
 		if err == nil {
 			value = buf.Bytes()
 		} else {
@@ -299,3 +307,33 @@ func NewTlv(t TlvType, id uint16, v any) *Tlv {
 		Children:   make([]*Tlv, 0),
 	}
 }
+
+// binaryValue convert v to a fixed size value that binary.Write accept
+func binaryValue(v any) any {
+	switch vv := v.(type) {
+	case int:
+		return int64(vv)
+	case uint:
+		return uint64(vv)
+	case time.Time:
+		return vv.Unix()
+	case ObjLink:
+		return [2]uint16{vv.ObjectId, vv.InstanceId}
+	}
+	return v
+}
+
+// binaryUnsigned decode a 1, 2, 4 or 8 bytes big endian unsigned integer
+func binaryUnsigned(data []byte) (uint64, error) {
+	switch len(data) {
+	case 1:
+		return uint64(data[0]), nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(data)), nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(data)), nil
+	case 8:
+		return binary.BigEndian.Uint64(data), nil
+	}
+	return 0, ErrInvalidLength
+}
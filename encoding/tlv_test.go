@@ -4,6 +4,7 @@ import (
 	"encoding/hex"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -197,3 +198,19 @@ func TestSimpleEncodeDecode(t *testing.T) {
 	data2 := EncodeTlv(tlvs)
 	assert.Equal(t, data, data2)
 }
+
+func TestNewTlv(t *testing.T) {
+	tlv := NewTlv(TlvSingleResource, 1, 300)
+	assert.Equal(t, []byte{0, 0, 0, 0, 0, 0, 0x01, 0x2c}, tlv.Value)
+	i, err := tlv.Integer()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(300), i)
+
+	tlv = NewTlv(TlvSingleResource, 13, time.Unix(1367491215, 0))
+	tm, err := tlv.Time()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1367491215), tm)
+
+	tlv = NewTlv(TlvSingleResource, 0, ObjLink{ObjectId: 0x42, InstanceId: 0x2})
+	assert.Equal(t, []byte{0x00, 0x42, 0x00, 0x02}, tlv.Value)
+}
@@ -0,0 +1,147 @@
+package encoding
+
+import (
+	"encoding/base64"
+	"errors"
+	"math"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrValueType = errors.New("value type mismatch")
+)
+
+// TypedValue is a Valuer backed by a Go value, formats that carry typed
+// values like SenML decode to it, Value is one of float64, int64, uint64,
+// string, bool, []byte, time.Time or ObjLink
+type TypedValue struct {
+	Value any
+	// Base64 is set if a string Value may be the base64 encoding of binary
+	// data, e.g. "sv" of LWM2M JSON that carry opaque values as string
+	Base64 bool
+}
+
+func (t *TypedValue) StringVal() string {
+	switch v := t.Value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case time.Time:
+		return strconv.FormatInt(v.Unix(), 10)
+	case ObjLink:
+		return v.String()
+	}
+	return ""
+}
+
+func (t *TypedValue) Integer() (val int64, err error) {
+	switch v := t.Value.(type) {
+	case int64:
+		return v, nil
+	case uint64:
+		if v > math.MaxInt64 {
+			return 0, ErrValueType
+		}
+		return int64(v), nil
+	case float64:
+		if v != math.Trunc(v) || v >= math.MaxInt64 || v < math.MinInt64 {
+			return 0, ErrValueType
+		}
+		return int64(v), nil
+	}
+	return 0, ErrValueType
+}
+
+func (t *TypedValue) Unsigned() (val uint64, err error) {
+	switch v := t.Value.(type) {
+	case uint64:
+		return v, nil
+	case int64:
+		if v < 0 {
+			return 0, ErrValueType
+		}
+		return uint64(v), nil
+	case float64:
+		if v != math.Trunc(v) || v < 0 || v >= math.MaxUint64 {
+			return 0, ErrValueType
+		}
+		return uint64(v), nil
+	}
+	return 0, ErrValueType
+}
+
+func (t *TypedValue) Float() (float64, error) {
+	switch v := t.Value.(type) {
+	case float64:
+		return v, nil
+	case int64:
+		return float64(v), nil
+	case uint64:
+		return float64(v), nil
+	}
+	return 0, ErrValueType
+}
+
+func (t *TypedValue) Boolean() (val bool, err error) {
+	if v, ok := t.Value.(bool); ok {
+		return v, nil
+	}
+	return false, ErrNotBoolean
+}
+
+func (t *TypedValue) Opaque() []byte {
+	switch v := t.Value.(type) {
+	case []byte:
+		return v
+	case string:
+		if !t.Base64 {
+			return []byte(v)
+		}
+		// formats without a binary type carry opaque as base64 string
+		opaque, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return nil
+		}
+		return opaque
+	}
+	return nil
+}
+
+func (t *TypedValue) Time() (int64, error) {
+	if v, ok := t.Value.(time.Time); ok {
+		return v.Unix(), nil
+	}
+	// Time is represented as a numeric value in seconds
+	return t.Integer()
+}
+
+func (t *TypedValue) ObjectLink() (uint16, uint16, error) {
+	switch v := t.Value.(type) {
+	case ObjLink:
+		return v.ObjectId, v.InstanceId, nil
+	case string:
+		l, err := ParseObjLink(v)
+		return l.ObjectId, l.InstanceId, err
+	}
+	return 0, 0, ErrValueType
+}
+
+func (t *TypedValue) Raw() []byte {
+	if v, ok := t.Value.([]byte); ok {
+		return v
+	}
+	return []byte(t.StringVal())
+}
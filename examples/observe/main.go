@@ -88,8 +88,10 @@ func onDeviceStateChange(e core.DeviceEvent, m core.Manager) {
 				res, _ := node.NewSingleResource(pr, val)
 				obi.SetResource(5750, res)
 			}
-			if err := device.Create(context.Background(), p, obi); err != nil {
+			if paths, err := device.Create(context.Background(), p, obi); err != nil {
 				glog.Warnf("Create object instance error %v", err)
+			} else {
+				glog.Infof("Created object instances %v", paths)
 			}
 		}
 	case core.DevicePostUpdate:
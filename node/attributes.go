@@ -0,0 +1,266 @@
+package node
+
+import (
+	"errors"
+	"fmt"
+	"github.com/yplam/lwm2m/encoding"
+	"strconv"
+)
+
+var (
+	ErrAttributeInvalidValue = errors.New("invalid attribute value")
+	ErrAttributeLevel        = errors.New("attribute is not allowed at path level")
+	ErrAttributeNotWritable  = errors.New("attribute is not writable")
+)
+
+// Attributes is the set of LWM2M attributes attached to a path, a nil
+// field is not set. Pmin, Pmax, Epmin and Epmax are in seconds.
+//
+// Ver, Dim, Ssid and Uri are reported by Discover only, the others are
+// notification attributes that can be written with Write-Attributes
+type Attributes struct {
+	Ver   *string
+	Dim   *int
+	Ssid  *uint16
+	Uri   *string
+	Pmin  *int
+	Pmax  *int
+	Gt    *float64
+	Lt    *float64
+	St    *float64
+	Epmin *int
+	Epmax *int
+	Edge  *bool
+	Con   *bool
+	Hqmax *int
+	// Extra hold parameters that are not LWM2M attributes, e.g. rt or ct
+	Extra []encoding.CoreLinkParam
+
+	cleared []string
+}
+
+// notificationAttributes are the attributes inherited from the upper
+// levels, in the order they are reported by EffectiveAttributes and
+// written by Queries
+var notificationAttributes = []string{"pmin", "pmax", "gt", "lt", "st", "epmin", "epmax", "edge", "con", "hqmax"}
+
+// get return the value of a notification attribute, nil if not set
+func (a *Attributes) get(name string) any {
+	switch name {
+	case "pmin":
+		if a.Pmin != nil {
+			return *a.Pmin
+		}
+	case "pmax":
+		if a.Pmax != nil {
+			return *a.Pmax
+		}
+	case "gt":
+		if a.Gt != nil {
+			return *a.Gt
+		}
+	case "lt":
+		if a.Lt != nil {
+			return *a.Lt
+		}
+	case "st":
+		if a.St != nil {
+			return *a.St
+		}
+	case "epmin":
+		if a.Epmin != nil {
+			return *a.Epmin
+		}
+	case "epmax":
+		if a.Epmax != nil {
+			return *a.Epmax
+		}
+	case "edge":
+		if a.Edge != nil {
+			return *a.Edge
+		}
+	case "con":
+		if a.Con != nil {
+			return *a.Con
+		}
+	case "hqmax":
+		if a.Hqmax != nil {
+			return *a.Hqmax
+		}
+	}
+	return nil
+}
+
+func (a *Attributes) set(param encoding.CoreLinkParam) error {
+	var err error
+	switch param.Key {
+	case "ver":
+		a.Ver = &param.Value
+	case "uri":
+		a.Uri = &param.Value
+	case "dim":
+		a.Dim, err = parseAttributeInt(param, 0, 255)
+	case "ssid":
+		var v *int
+		if v, err = parseAttributeInt(param, 0, 65535); err == nil {
+			ssid := uint16(*v)
+			a.Ssid = &ssid
+		}
+	case "pmin":
+		a.Pmin, err = parseAttributeInt(param, 0, -1)
+	case "pmax":
+		a.Pmax, err = parseAttributeInt(param, 0, -1)
+	case "epmin":
+		a.Epmin, err = parseAttributeInt(param, 0, -1)
+	case "epmax":
+		a.Epmax, err = parseAttributeInt(param, 0, -1)
+	case "hqmax":
+		a.Hqmax, err = parseAttributeInt(param, 0, -1)
+	case "gt":
+		a.Gt, err = parseAttributeFloat(param)
+	case "lt":
+		a.Lt, err = parseAttributeFloat(param)
+	case "st":
+		a.St, err = parseAttributeFloat(param)
+	case "edge":
+		a.Edge, err = parseAttributeBool(param)
+	case "con":
+		a.Con, err = parseAttributeBool(param)
+	default:
+		a.Extra = append(a.Extra, param)
+	}
+	return err
+}
+
+// parseAttributeInt parse an integer attribute in [min, max], a negative
+// max means no upper bound
+func parseAttributeInt(param encoding.CoreLinkParam, min, max int) (*int, error) {
+	v, err := strconv.Atoi(param.Value)
+	if err != nil || v < min || (max >= 0 && v > max) {
+		return nil, fmt.Errorf("%w: %s=%q", ErrAttributeInvalidValue, param.Key, param.Value)
+	}
+	return &v, nil
+}
+
+func parseAttributeFloat(param encoding.CoreLinkParam) (*float64, error) {
+	v, err := strconv.ParseFloat(param.Value, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s=%q", ErrAttributeInvalidValue, param.Key, param.Value)
+	}
+	return &v, nil
+}
+
+func parseAttributeBool(param encoding.CoreLinkParam) (*bool, error) {
+	var v bool
+	switch param.Value {
+	case "1":
+		v = true
+	case "0":
+	default:
+		return nil, fmt.Errorf("%w: %s=%q", ErrAttributeInvalidValue, param.Key, param.Value)
+	}
+	return &v, nil
+}
+
+// Clear mark notification attributes to be removed by Write-Attributes
+func (a *Attributes) Clear(names ...string) {
+	a.cleared = append(a.cleared, names...)
+}
+
+// Validate check the attributes can be written to p, see the attribute
+// table in LWM2M 1.2 Core section 5.1.2. gt, lt and st are only allowed on
+// numeric resources and edge on boolean resources if the registry know the
+// resource type. Nil attributes are ErrEmpty
+func (a *Attributes) Validate(p Path) error {
+	if a == nil {
+		return ErrEmpty
+	}
+	if a.Ver != nil || a.Dim != nil || a.Ssid != nil || a.Uri != nil || len(a.Extra) > 0 {
+		return ErrAttributeNotWritable
+	}
+	for _, name := range a.cleared {
+		if !isNotificationAttribute(name) {
+			return fmt.Errorf("%w: %s", ErrAttributeNotWritable, name)
+		}
+		if a.get(name) != nil {
+			return fmt.Errorf("%w: %s is both set and cleared", ErrAttributeInvalidValue, name)
+		}
+	}
+	if p.IsRoot() {
+		return ErrAttributeLevel
+	}
+	numeric := a.Gt != nil || a.Lt != nil || a.St != nil
+	if numeric || a.Edge != nil {
+		if !p.IsResource() && !p.IsResourceInstance() {
+			return ErrAttributeLevel
+		}
+		if t, err := GetRegistry().DetectResourceType(p); err == nil && t != R_NONE {
+			if numeric && t != R_INTEGER && t != R_UNSIGNED && t != R_FLOAT {
+				return fmt.Errorf("%w: gt, lt and st need a numeric resource", ErrAttributeLevel)
+			}
+			if a.Edge != nil && t != R_BOOLEAN {
+				return fmt.Errorf("%w: edge need a boolean resource", ErrAttributeLevel)
+			}
+		}
+	}
+	for _, v := range []*int{a.Pmin, a.Pmax, a.Epmin, a.Epmax, a.Hqmax} {
+		if v != nil && *v < 0 {
+			return ErrAttributeInvalidValue
+		}
+	}
+	if a.St != nil && *a.St <= 0 {
+		return ErrAttributeInvalidValue
+	}
+	if a.Pmin != nil && a.Pmax != nil && *a.Pmax < *a.Pmin {
+		return fmt.Errorf("%w: pmax less than pmin", ErrAttributeInvalidValue)
+	}
+	if a.Epmin != nil && a.Epmax != nil && *a.Epmax < *a.Epmin {
+		return fmt.Errorf("%w: epmax less than epmin", ErrAttributeInvalidValue)
+	}
+	if a.Gt != nil && a.Lt != nil {
+		st := 0.0
+		if a.St != nil {
+			st = *a.St
+		}
+		if *a.Lt+2*st >= *a.Gt {
+			return fmt.Errorf("%w: lt + 2*st must be less than gt", ErrAttributeInvalidValue)
+		}
+	}
+	return nil
+}
+
+// Queries return the URI query parameters of a Write-Attributes request,
+// a cleared attribute is sent without value
+func (a *Attributes) Queries() []string {
+	queries := make([]string, 0)
+	if a == nil {
+		return queries
+	}
+	for _, name := range notificationAttributes {
+		var q string
+		switch v := a.get(name).(type) {
+		case nil:
+			continue
+		case int:
+			q = strconv.Itoa(v)
+		case float64:
+			q = strconv.FormatFloat(v, 'g', -1, 64)
+		case bool:
+			q = "0"
+			if v {
+				q = "1"
+			}
+		}
+		queries = append(queries, name+"="+q)
+	}
+	return append(queries, a.cleared...)
+}
+
+func isNotificationAttribute(name string) bool {
+	for _, n := range notificationAttributes {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package node
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttributesQueries(t *testing.T) {
+	pmin, pmax := 10, 60
+	gt, st := 30.5, 1.0
+	edge := true
+	attrs := &Attributes{Pmin: &pmin, Pmax: &pmax, Gt: &gt, St: &st}
+	attrs.Clear("lt")
+	assert.Nil(t, attrs.Validate(NewResourcePath(3303, 0, 5700)))
+	assert.Equal(t, []string{"pmin=10", "pmax=60", "gt=30.5", "st=1", "lt"}, attrs.Queries())
+
+	attrs = &Attributes{Edge: &edge}
+	assert.Nil(t, attrs.Validate(NewResourcePath(3342, 0, 5500)))
+	assert.Equal(t, []string{"edge=1"}, attrs.Queries())
+}
+
+func TestAttributesValidate(t *testing.T) {
+	pmin, pmax := 60, 10
+	gt, lt, st := 10.0, 5.0, 3.0
+	edge := true
+	ver := "1.1"
+
+	var none *Attributes
+	assert.Equal(t, ErrEmpty, none.Validate(NewObjectPath(3)))
+	assert.Equal(t, 0, len(none.Queries()))
+
+	attrs := &Attributes{Pmin: &pmin}
+	assert.Nil(t, attrs.Validate(NewObjectPath(3)))
+	assert.Nil(t, attrs.Validate(NewObjectInstancePath(3, 0)))
+	root, err := NewPathFromString("/")
+	assert.Nil(t, err)
+	assert.ErrorIs(t, attrs.Validate(root), ErrAttributeLevel)
+
+	attrs = &Attributes{Pmin: &pmin, Pmax: &pmax}
+	assert.ErrorIs(t, attrs.Validate(NewObjectPath(3)), ErrAttributeInvalidValue)
+
+	attrs = &Attributes{Gt: &gt}
+	assert.ErrorIs(t, attrs.Validate(NewObjectInstancePath(3303, 0)), ErrAttributeLevel)
+	// /3/0/0 manufacturer is a string resource
+	assert.ErrorIs(t, attrs.Validate(NewResourcePath(3, 0, 0)), ErrAttributeLevel)
+	assert.Nil(t, attrs.Validate(NewResourceInstancePath(3, 0, 7, 0)))
+
+	attrs = &Attributes{Gt: &gt, Lt: &lt, St: &st}
+	assert.ErrorIs(t, attrs.Validate(NewResourcePath(3303, 0, 5700)), ErrAttributeInvalidValue)
+
+	attrs = &Attributes{Edge: &edge}
+	assert.ErrorIs(t, attrs.Validate(NewResourcePath(3303, 0, 5700)), ErrAttributeLevel)
+
+	attrs = &Attributes{Ver: &ver}
+	assert.ErrorIs(t, attrs.Validate(NewObjectPath(3)), ErrAttributeNotWritable)
+
+	attrs = &Attributes{}
+	attrs.Clear("ssid")
+	assert.ErrorIs(t, attrs.Validate(NewObjectPath(3)), ErrAttributeNotWritable)
+	attrs = &Attributes{Pmin: &pmin}
+	attrs.Clear("pmin")
+	assert.ErrorIs(t, attrs.Validate(NewObjectPath(3)), ErrAttributeInvalidValue)
+}
@@ -0,0 +1,146 @@
+package node
+
+import (
+	"fmt"
+	"github.com/yplam/lwm2m/encoding"
+)
+
+// EffectiveAttribute is a notification attribute in effect on a path,
+// Level is the path the attribute is attached to, it is an ancestor of the
+// path if the attribute is inherited
+type EffectiveAttribute struct {
+	Name  string
+	Value any
+	Level Path
+}
+
+// DiscoverEntry is an object, object instance, resource or resource
+// instance reported by a Discover operation. Entries that are not in the
+// response but have reported children are created without attributes
+type DiscoverEntry struct {
+	Path       Path
+	Attributes Attributes
+	Children   map[uint16]*DiscoverEntry
+	// Reported is false for entries created for the tree structure
+	Reported bool
+}
+
+func newDiscoverEntry(p Path) *DiscoverEntry {
+	return &DiscoverEntry{
+		Path:     p,
+		Children: make(map[uint16]*DiscoverEntry),
+	}
+}
+
+// Discovery is the result of a Discover operation
+type Discovery struct {
+	// Version is the "lwm2m" parameter of the root link of a bootstrap
+	// discover response, e.g. "1.1"
+	Version string
+	Objects map[uint16]*DiscoverEntry
+	// Root hold the parameters of the root link "</>", if any
+	Root *encoding.CoreLink
+}
+
+// NewDiscovery build a Discovery from the links of a Discover response
+func NewDiscovery(links []*encoding.CoreLink) (*Discovery, error) {
+	d := &Discovery{
+		Objects: make(map[uint16]*DiscoverEntry),
+	}
+	for _, l := range links {
+		p, err := NewPathFromString(l.Uri)
+		if err != nil {
+			return nil, fmt.Errorf("discover link %q: %w", l.Uri, err)
+		}
+		if p.IsRoot() {
+			d.Root = l
+			if v, ok := l.Param("lwm2m"); ok {
+				d.Version = v
+			}
+			continue
+		}
+		e := d.entry(p)
+		e.Reported = true
+		for _, param := range l.Params {
+			if err = e.Attributes.set(param); err != nil {
+				return nil, fmt.Errorf("discover link %q: %w", l.Uri, err)
+			}
+		}
+	}
+	return d, nil
+}
+
+// entry return the entry of p, entries of p and its ancestors are created
+// if not exist
+func (d *Discovery) entry(p Path) *DiscoverEntry {
+	ids := p.ids()
+	e, ok := d.Objects[ids[0]]
+	if !ok {
+		e = newDiscoverEntry(NewObjectPath(ids[0]))
+		d.Objects[ids[0]] = e
+	}
+	for i := 1; i < len(ids); i++ {
+		c, ok := e.Children[ids[i]]
+		if !ok {
+			cp, _ := newPathFromIds(ids[:i+1])
+			c = newDiscoverEntry(cp)
+			e.Children[ids[i]] = c
+		}
+		e = c
+	}
+	return e
+}
+
+// Get return the entry of p
+func (d *Discovery) Get(p Path) (*DiscoverEntry, error) {
+	ids := p.ids()
+	if len(ids) == 0 {
+		return nil, ErrNotFound
+	}
+	e, ok := d.Objects[ids[0]]
+	for i := 1; ok && i < len(ids); i++ {
+		e, ok = e.Children[ids[i]]
+	}
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return e, nil
+}
+
+// Entries return all entries of the tree, parents come before their
+// children and ids are in ascending order
+func (d *Discovery) Entries() []*DiscoverEntry {
+	entries := make([]*DiscoverEntry, 0)
+	var walk func(m map[uint16]*DiscoverEntry)
+	walk = func(m map[uint16]*DiscoverEntry) {
+		for _, id := range sortedIds(m) {
+			entries = append(entries, m[id])
+			walk(m[id].Children)
+		}
+	}
+	walk(d.Objects)
+	return entries
+}
+
+// EffectiveAttributes return the notification attributes in effect on p,
+// an attribute attached to p override the ones of its ancestors
+func (d *Discovery) EffectiveAttributes(p Path) []EffectiveAttribute {
+	chain := make([]*DiscoverEntry, 0, 4)
+	ids := p.ids()
+	for i := len(ids); i > 0; i-- {
+		ap, _ := newPathFromIds(ids[:i])
+		if e, err := d.Get(ap); err == nil {
+			chain = append(chain, e)
+		}
+	}
+	attrs := make([]EffectiveAttribute, 0)
+	for _, name := range notificationAttributes {
+		for _, e := range chain {
+			if v := e.Attributes.get(name); v != nil {
+				attrs = append(attrs, EffectiveAttribute{Name: name, Value: v, Level: e.Path})
+				break
+			}
+		}
+	}
+	return attrs
+}
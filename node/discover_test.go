@@ -0,0 +1,71 @@
+package node
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yplam/lwm2m/encoding"
+)
+
+func TestDiscovery(t *testing.T) {
+	links, err := encoding.CoreLinksFromString(`</3>;ver=1.1;pmin=10,</3/0>;pmax=60,</3/0/1>,` +
+		`</3/0/7>;dim=2;gt=50;pmin=5,</3/0/7/1>;st=0.5,</5/0/3>`)
+	assert.Nil(t, err)
+	d, err := NewDiscovery(links)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(d.Objects))
+
+	e, err := d.Get(NewObjectPath(3))
+	assert.Nil(t, err)
+	assert.True(t, e.Reported)
+	assert.Equal(t, "1.1", *e.Attributes.Ver)
+	assert.Equal(t, 10, *e.Attributes.Pmin)
+
+	e, err = d.Get(NewResourcePath(3, 0, 7))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, *e.Attributes.Dim)
+	assert.Equal(t, 50.0, *e.Attributes.Gt)
+	assert.Equal(t, 1, len(e.Children))
+
+	e, err = d.Get(NewObjectInstancePath(5, 0))
+	assert.Nil(t, err)
+	assert.False(t, e.Reported)
+	_, err = d.Get(NewObjectPath(4))
+	assert.Equal(t, ErrNotFound, err)
+
+	entries := d.Entries()
+	paths := make([]string, 0)
+	for _, e := range entries {
+		paths = append(paths, e.Path.String())
+	}
+	assert.Equal(t, []string{"/3", "/3/0", "/3/0/1", "/3/0/7", "/3/0/7/1", "/5", "/5/0", "/5/0/3"}, paths)
+
+	assert.Equal(t, []EffectiveAttribute{
+		{Name: "pmin", Value: 5, Level: NewResourcePath(3, 0, 7)},
+		{Name: "pmax", Value: 60, Level: NewObjectInstancePath(3, 0)},
+		{Name: "gt", Value: 50.0, Level: NewResourcePath(3, 0, 7)},
+		{Name: "st", Value: 0.5, Level: NewResourceInstancePath(3, 0, 7, 1)},
+	}, d.EffectiveAttributes(NewResourceInstancePath(3, 0, 7, 1)))
+	assert.Equal(t, []EffectiveAttribute{
+		{Name: "pmin", Value: 10, Level: NewObjectPath(3)},
+		{Name: "pmax", Value: 60, Level: NewObjectInstancePath(3, 0)},
+	}, d.EffectiveAttributes(NewResourcePath(3, 0, 1)))
+}
+
+func TestBootstrapDiscovery(t *testing.T) {
+	links, err := encoding.CoreLinksFromString(`</>;lwm2m=1.1,</0/0>;ssid=101;uri="coap://server:5683",` +
+		`</0/1>,</1/0>;ssid=101,</3/0>`)
+	assert.Nil(t, err)
+	d, err := NewDiscovery(links)
+	assert.Nil(t, err)
+	assert.Equal(t, "1.1", d.Version)
+	e, err := d.Get(NewObjectInstancePath(0, 0))
+	assert.Nil(t, err)
+	assert.Equal(t, uint16(101), *e.Attributes.Ssid)
+	assert.Equal(t, "coap://server:5683", *e.Attributes.Uri)
+
+	links, err = encoding.CoreLinksFromString(`</3/0>;pmin=abc`)
+	assert.Nil(t, err)
+	_, err = NewDiscovery(links)
+	assert.ErrorIs(t, err, ErrAttributeInvalidValue)
+}
@@ -0,0 +1,42 @@
+package node
+
+import (
+	"github.com/yplam/lwm2m/encoding"
+)
+
+func decodeLwm2mCborMessage(basePath Path, records []*encoding.Lwm2mCborRecord) ([]Node, error) {
+	values := make([]resourceValue, 0, len(records))
+	for _, r := range records {
+		if r.Value == nil {
+			continue
+		}
+		p, err := newPathFromIds(r.Path)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, resourceValue{
+			path: p,
+			data: &encoding.TypedValue{Value: r.Value},
+		})
+	}
+	return buildNodes(basePath, values)
+}
+
+func encodeLwm2mCborMessage(nodes []Node) ([]*encoding.Lwm2mCborRecord, error) {
+	records := make([]*encoding.Lwm2mCborRecord, 0)
+	err := walkResourceInstances(nodes, func(p Path, ri *ResourceInstance) error {
+		v, err := flatValue(ri)
+		if err != nil {
+			return err
+		}
+		records = append(records, &encoding.Lwm2mCborRecord{
+			Path:  p.ids(),
+			Value: v,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
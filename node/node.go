@@ -9,6 +9,8 @@ import (
 	"github.com/yplam/lwm2m/encoding"
 	"io"
 	"reflect"
+	"sort"
+	"time"
 )
 
 var (
@@ -17,6 +19,8 @@ var (
 	ErrContentFormatNotSupport = errors.New("content format not support")
 	ErrPathNotMatch            = errors.New("wrong path type")
 	ErrMediaTypePathConflict   = errors.New("data path and the media type are in conflict")
+	ErrResourceType            = errors.New("value does not match the resource type")
+	ErrOperationNotAllowed     = errors.New("operation not allowed on the resource")
 )
 
 // A Node is the base type of lwm2m message, can be one of
@@ -73,11 +77,50 @@ func DecodeMessage(basePath Path, msg *pool.Message) (nodes []Node, err error) {
 			nodes = append(nodes, n)
 		}
 		return nodes, nil
+	case message.AppCBOR:
+		if !basePath.IsResource() && !basePath.IsResourceInstance() {
+			return nil, ErrMediaTypePathConflict
+		}
+		cv := encoding.NewCborRaw(content)
+		if n, err := NewResource(basePath, basePath.IsResourceInstance()); err == nil {
+			if ri, err := NewResourceInstance(basePath, cv); err == nil {
+				n.SetInstance(ri)
+			}
+			nodes = append(nodes, n)
+		}
+		return nodes, nil
+	case message.AppSenmlJSON:
+		records, errD := encoding.DecodeSenMLJSON(content)
+		if errD != nil {
+			err = errD
+			return
+		}
+		return decodeSenMLMessage(basePath, records, false)
+	case message.AppSenmlCbor:
+		records, errD := encoding.DecodeSenMLCbor(content)
+		if errD != nil {
+			err = errD
+			return
+		}
+		return decodeSenMLMessage(basePath, records, false)
+	case message.AppLwm2mCbor:
+		records, errD := encoding.DecodeLwm2mCbor(content)
+		if errD != nil {
+			err = errD
+			return
+		}
+		return decodeLwm2mCborMessage(basePath, records)
+	case message.AppLwm2mJSON:
+		records, errD := encoding.DecodeLwm2mJSON(content)
+		if errD != nil {
+			err = errD
+			return
+		}
+		return decodeSenMLMessage(basePath, records, true)
 	default:
 		err = ErrContentFormatNotSupport
 		return
 	}
-	return
 }
 
 func decodeTLVMessage(p Path, tlvs []*encoding.Tlv) ([]Node, error) {
@@ -135,6 +178,161 @@ func decodeTLVMessage(p Path, tlvs []*encoding.Tlv) ([]Node, error) {
 	return nodes, nil
 }
 
+// resourceValue is a resource or resource instance value with its full
+// path, flat formats like SenML are decoded to a list of resourceValue
+type resourceValue struct {
+	path Path
+	data encoding.Valuer
+	// t is the time of the value, zero if the record has none
+	t time.Time
+}
+
+// buildNodes group flat resource values to a node tree, returned nodes are
+// at the level below basePath, same as decodeTLVMessage
+func buildNodes(basePath Path, values []resourceValue) ([]Node, error) {
+	objs := make(map[uint16]*Object)
+	for _, v := range values {
+		if !(v.path.IsResource() || v.path.IsResourceInstance()) {
+			return nil, ErrPathNotMatch
+		}
+		if !v.path.IsChildOfOrEq(basePath) && !basePath.IsChildOfOrEq(v.path) {
+			return nil, ErrPathNotMatch
+		}
+		oid, iid, rid := uint16(v.path.objectId), uint16(v.path.objectInstanceId), uint16(v.path.resourceId)
+		obj, ok := objs[oid]
+		if !ok {
+			obj = NewObject(oid)
+			objs[oid] = obj
+		}
+		oi, ok := obj.Instances[iid]
+		if !ok {
+			oi = NewObjectInstance(iid)
+			obj.Instances[iid] = oi
+		}
+		res, ok := oi.Resources[rid]
+		if !ok {
+			res, _ = NewResource(NewResourcePath(oid, iid, rid), false)
+			oi.Resources[rid] = res
+		}
+		if v.path.IsResourceInstance() {
+			res.isMultiple = true
+		}
+		ri, err := NewResourceInstance(v.path, v.data)
+		if errors.Is(err, ErrNotFound) {
+			ri, err = newUnknownResourceInstance(v.path, v.data)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", v.path.String(), err)
+		}
+		if ri.resType != R_NONE && ri.Value() == nil {
+			return nil, fmt.Errorf("%s: %w", v.path.String(), ErrResourceType)
+		}
+		ri.timestamp = v.t
+		_ = res.addSample(ri)
+	}
+	nodes := make([]Node, 0)
+	for _, oid := range sortedIds(objs) {
+		obj := objs[oid]
+		if basePath.IsRoot() {
+			nodes = append(nodes, obj)
+			continue
+		}
+		for _, iid := range sortedIds(obj.Instances) {
+			oi := obj.Instances[iid]
+			if basePath.IsObject() {
+				nodes = append(nodes, oi)
+				continue
+			}
+			for _, rid := range sortedIds(oi.Resources) {
+				nodes = append(nodes, oi.Resources[rid])
+			}
+		}
+	}
+	return nodes, nil
+}
+
+// walkResourceInstances call fn with every resource instance of nodes and
+// the full path it should be addressed with in a flat format, a single
+// resource is addressed by the resource path
+func walkResourceInstances(nodes []Node, fn func(p Path, ri *ResourceInstance) error) error {
+	for _, node := range nodes {
+		switch n := node.(type) {
+		case *Object:
+			for _, iid := range sortedIds(n.Instances) {
+				oi := n.Instances[iid]
+				for _, rid := range sortedIds(oi.Resources) {
+					err := walkResource(oi.Resources[rid], int32(n.Id), int32(oi.Id), fn)
+					if err != nil {
+						return err
+					}
+				}
+			}
+		case *ObjectInstance:
+			for _, rid := range sortedIds(n.Resources) {
+				if err := walkResource(n.Resources[rid], -1, int32(n.Id), fn); err != nil {
+					return err
+				}
+			}
+		case *Resource:
+			if err := walkResource(n, -1, -1, fn); err != nil {
+				return err
+			}
+		case *ResourceInstance:
+			if err := fn(n.path, n); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func walkResource(r *Resource, oid, iid int32, fn func(p Path, ri *ResourceInstance) error) error {
+	p := r.path
+	if oid > -1 {
+		p.objectId = oid
+	}
+	if iid > -1 {
+		p.objectInstanceId = iid
+	}
+	p.resourceId = int32(r.id)
+	p.resourceInstanceId = -1
+	if p.objectId < 0 || p.objectInstanceId < 0 {
+		return ErrPathNotMatch
+	}
+	for _, id := range sortedIds(r.instances) {
+		rp := p
+		if r.isMultiple {
+			rp.resourceInstanceId = int32(id)
+		}
+		if err := fn(rp, r.instances[id]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// flatValue return the Go value of ri to be encoded in a typed format
+func flatValue(ri *ResourceInstance) (any, error) {
+	switch v := ri.Value().(type) {
+	case string, int64, uint64, float64, bool, []byte, time.Time, encoding.ObjLink:
+		return v, nil
+	case *encoding.TypedValue:
+		return v.Value, nil
+	case encoding.Valuer:
+		return v.StringVal(), nil
+	}
+	return nil, encoding.ErrUnknownType
+}
+
+func sortedIds[T any](m map[uint16]T) []uint16 {
+	ids := make([]uint16, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
 func EncodeMessage(t message.MediaType, node []Node) (io.ReadSeeker, error) {
 	if len(node) == 0 {
 		return nil, ErrEmpty
@@ -159,49 +357,89 @@ func EncodeMessage(t message.MediaType, node []Node) (io.ReadSeeker, error) {
 			return nil, err
 		}
 		return bytes.NewReader(om.Raw()), nil
-	}
-	return nil, ErrEmpty
-}
-
-func encodeTextMessage(node []Node) (*encoding.PlainTextValue, error) {
-	if len(node) != 1 {
-		return nil, ErrMediaTypePathConflict
-	}
-	if rr, ok := node[0].(*Resource); ok {
-		if rr.InstanceCount() != 1 {
-			return nil, ErrMediaTypePathConflict
+	case message.AppCBOR:
+		cv, err := encodeCborMessage(node)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(cv.Raw()), nil
+	case message.AppSenmlJSON:
+		records, err := encodeSenMLMessage(node)
+		if err != nil {
+			return nil, err
+		}
+		c, err := encoding.EncodeSenMLJSON(records)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(c), nil
+	case message.AppSenmlCbor:
+		records, err := encodeSenMLMessage(node)
+		if err != nil {
+			return nil, err
+		}
+		c, err := encoding.EncodeSenMLCbor(records)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.NewReader(c), nil
+	case message.AppLwm2mCbor:
+		records, err := encodeLwm2mCborMessage(node)
+		if err != nil {
+			return nil, err
 		}
-		ri, err := rr.GetInstance(0)
+		c, err := encoding.EncodeLwm2mCbor(records)
 		if err != nil {
 			return nil, err
 		}
-		vv := ri.Value()
-		pt, err := encoding.NewPlainTextValue(vv)
+		return bytes.NewReader(c), nil
+	case message.AppLwm2mJSON:
+		records, err := encodeSenMLMessage(node)
+		if err != nil {
+			return nil, err
+		}
+		c, err := encoding.EncodeLwm2mJSON(records)
 		if err != nil {
 			return nil, err
 		}
-		return pt, nil
+		return bytes.NewReader(c), nil
 	}
-	return nil, ErrMediaTypePathConflict
+	return nil, ErrEmpty
+}
+
+func encodeTextMessage(node []Node) (*encoding.PlainTextValue, error) {
+	ri, err := singleResourceInstance(node)
+	if err != nil {
+		return nil, err
+	}
+	return encoding.NewPlainTextValue(ri.Value())
 }
 
 func encodeOpaqueMessage(node []Node) (*encoding.OpaqueValue, error) {
+	ri, err := singleResourceInstance(node)
+	if err != nil {
+		return nil, err
+	}
+	return encoding.NewOpaqueValue(ri.Value())
+}
+
+// singleResourceInstance return the resource instance of content formats
+// that hold a single value, node must be a resource with one instance or a
+// resource instance
+func singleResourceInstance(node []Node) (*ResourceInstance, error) {
 	if len(node) != 1 {
 		return nil, ErrMediaTypePathConflict
 	}
-	if rr, ok := node[0].(*Resource); ok {
-		if rr.InstanceCount() != 1 {
+	switch n := node[0].(type) {
+	case *Resource:
+		if n.InstanceCount() != 1 {
 			return nil, ErrMediaTypePathConflict
 		}
-		ri, err := rr.GetInstance(0)
-		if err != nil {
-			return nil, err
-		}
-		om, err := encoding.NewOpaqueValue(ri.Value())
-		if err != nil {
-			return nil, err
+		for _, ri := range n.instances {
+			return ri, nil
 		}
-		return om, nil
+	case *ResourceInstance:
+		return n, nil
 	}
 	return nil, ErrMediaTypePathConflict
 }
@@ -214,20 +452,21 @@ func encodeTLVMessage(nodes []Node) ([]*encoding.Tlv, error) {
 			if n, okay := node.(*Resource); okay {
 				if n.isMultiple {
 					tlv := encoding.NewTlv(encoding.TlvMultipleResource, n.id, []byte{})
-					for _, ri := range n.instances {
-						child := encoding.NewTlv(encoding.TlvMultipleResourceItem, ri.id, ri.Data().Raw())
+					for _, id := range sortedIds(n.instances) {
+						ri := n.instances[id]
+						child := encoding.NewTlv(encoding.TlvMultipleResourceItem, ri.id, tlvValue(ri))
 						tlv.Children = append(tlv.Children, child)
 					}
 					tlvs = append(tlvs, tlv)
-				} else {
-					tlv := encoding.NewTlv(encoding.TlvSingleResource, n.id, n.Data().Raw())
+				} else if ri, err := n.GetInstance(0); err == nil {
+					tlv := encoding.NewTlv(encoding.TlvSingleResource, n.id, tlvValue(ri))
 					tlvs = append(tlvs, tlv)
 				}
 			}
 		case *Object:
 			if n, okay := node.(*Object); okay {
-				for _, oi := range n.Instances {
-					if eoi, err := encodeTLVMessage([]Node{oi}); err == nil {
+				for _, iid := range sortedIds(n.Instances) {
+					if eoi, err := encodeTLVMessage([]Node{n.Instances[iid]}); err == nil {
 						tlvs = append(tlvs, eoi...)
 					}
 				}
@@ -235,8 +474,8 @@ func encodeTLVMessage(nodes []Node) ([]*encoding.Tlv, error) {
 		case *ObjectInstance:
 			if n, okay := node.(*ObjectInstance); okay {
 				tlv := encoding.NewTlv(encoding.TlvObjectInstance, n.Id, []byte{})
-				for _, ri := range n.Resources {
-					if eri, err := encodeTLVMessage([]Node{ri}); err == nil {
+				for _, rid := range sortedIds(n.Resources) {
+					if eri, err := encodeTLVMessage([]Node{n.Resources[rid]}); err == nil {
 						tlv.Children = append(tlv.Children, eri...)
 					}
 				}
@@ -244,7 +483,7 @@ func encodeTLVMessage(nodes []Node) ([]*encoding.Tlv, error) {
 			}
 		case *ResourceInstance:
 			if n, okay := node.(*ResourceInstance); okay {
-				tlv := encoding.NewTlv(encoding.TlvMultipleResourceItem, n.id, n.Data().Raw())
+				tlv := encoding.NewTlv(encoding.TlvMultipleResourceItem, n.id, tlvValue(n))
 				tlvs = append(tlvs, tlv)
 			}
 		}
@@ -253,6 +492,17 @@ func encodeTLVMessage(nodes []Node) ([]*encoding.Tlv, error) {
 	return tlvs, nil
 }
 
+// tlvValue return the value of ri to be encoded in TLV, raw data is used
+// for resources of unknown type
+func tlvValue(ri *ResourceInstance) any {
+	switch v := ri.Value().(type) {
+	case nil, encoding.Valuer:
+		return ri.Data().Raw()
+	default:
+		return v
+	}
+}
+
 func GetAllResources(nodes []Node, parentPath Path) (map[Path]*Resource, error) {
 	values := make(map[Path]*Resource)
 	for _, node := range nodes {
@@ -396,3 +646,107 @@ func GetResourceByPath(nodes []Node, p Path) (r *Resource, err error) {
 	err = ErrNotFound
 	return
 }
+
+// GetNodesByPath return the nodes of the subtree p of nodes, at the level
+// below p as if p was read, e.g. the resources of an object instance. A
+// resource instance path return its resource with only that instance
+func GetNodesByPath(nodes []Node, p Path) ([]Node, error) {
+	if p.IsRoot() {
+		return nodes, nil
+	}
+	base := p
+	if p.IsResourceInstance() {
+		base, _ = p.Parent()
+	}
+	resources, err := GetAllResources(nodes, base)
+	if err != nil {
+		return nil, err
+	}
+	if p.IsResourceInstance() {
+		ri, err := resources[base].GetInstance(uint16(p.resourceInstanceId))
+		if err != nil {
+			return nil, ErrNotFound
+		}
+		r, _ := NewResource(base, true)
+		_ = r.SetInstance(ri)
+		if h := resources[base].history[ri.id]; len(h) > 0 {
+			r.history = map[uint16][]*ResourceInstance{ri.id: h}
+		}
+		return []Node{r}, nil
+	}
+	if p.IsResource() {
+		return []Node{resources[p]}, nil
+	}
+	objs := make(map[uint16]*Object)
+	for rp, r := range resources {
+		oid, iid := uint16(rp.objectId), uint16(rp.objectInstanceId)
+		obj, ok := objs[oid]
+		if !ok {
+			obj = NewObject(oid)
+			objs[oid] = obj
+		}
+		oi, ok := obj.Instances[iid]
+		if !ok {
+			oi = NewObjectInstance(iid)
+			obj.Instances[iid] = oi
+		}
+		oi.Resources[uint16(rp.resourceId)] = r
+	}
+	result := make([]Node, 0)
+	for _, oid := range sortedIds(objs) {
+		obj := objs[oid]
+		for _, iid := range sortedIds(obj.Instances) {
+			oi := obj.Instances[iid]
+			if p.IsObject() {
+				result = append(result, oi)
+				continue
+			}
+			for _, rid := range sortedIds(oi.Resources) {
+				result = append(result, oi.Resources[rid])
+			}
+		}
+	}
+	return result, nil
+}
+
+// GetHistory return the values of the resources of nodes under parentPath,
+// oldest first, keyed by the path of the resource for single instance
+// resources and of the resource instance otherwise
+func GetHistory(nodes []Node, parentPath Path) (map[Path][]*ResourceInstance, error) {
+	base := parentPath
+	if parentPath.IsResourceInstance() {
+		base, _ = parentPath.Parent()
+	}
+	resources, err := GetAllResources(nodes, base)
+	if err != nil {
+		return nil, err
+	}
+	history := make(map[Path][]*ResourceInstance)
+	for p, r := range resources {
+		for id := range r.instances {
+			key := p
+			if r.isMultiple {
+				key = NewResourceInstancePath(uint16(p.objectId), uint16(p.objectInstanceId), uint16(p.resourceId), id)
+			}
+			if !key.IsChildOfOrEq(parentPath) {
+				continue
+			}
+			history[key] = r.History(id)
+		}
+	}
+	return history, nil
+}
+
+// encodeCborMessage encode a single resource value, node may be a single
+// instance resource or a resource instance
+func encodeCborMessage(node []Node) (*encoding.CborValue, error) {
+	ri, err := singleResourceInstance(node)
+	if err != nil {
+		return nil, err
+	}
+	v, err := flatValue(ri)
+	if err != nil {
+		return nil, err
+	}
+	return encoding.NewCborValue(v)
+}
@@ -7,8 +7,12 @@ import (
 	"github.com/plgd-dev/go-coap/v3/message"
 	"github.com/plgd-dev/go-coap/v3/message/pool"
 	"github.com/stretchr/testify/assert"
+	"github.com/yplam/lwm2m/encoding"
+	"io"
+	"math"
 	"strings"
 	"testing"
+	"time"
 )
 
 func _strToByte(str string) (dst []byte, err error) {
@@ -420,3 +424,503 @@ func TestDecodeOpaque(t *testing.T) {
 		}
 	}
 }
+
+func decodeSenMLJSON(t *testing.T, p Path, str string) ([]Node, error) {
+	msg := pool.NewMessage(context.Background())
+	msg.SetContentFormat(message.AppSenmlJSON)
+	msg.SetBody(bytes.NewReader([]byte(str)))
+	return DecodeMessage(p, msg)
+}
+
+const deviceSenMLJSON = `[{"bn":"/3/0/","n":"0","vs":"Open Mobile Alliance"},
+{"n":"1","vs":"Lightweight M2M Client"},
+{"n":"2","vs":"345000123"},
+{"n":"3","vs":"1.0"},
+{"n":"6/0","v":1},
+{"n":"6/1","v":5},
+{"n":"7/0","v":3800},
+{"n":"7/1","v":5000},
+{"n":"8/0","v":125},
+{"n":"8/1","v":900},
+{"n":"9","v":100},
+{"n":"10","v":15},
+{"n":"11/0","v":0},
+{"n":"13","v":1367491215},
+{"n":"14","vs":"+02:00"},
+{"n":"16","vs":"U"}]`
+
+func TestDecodeSenMLJSON(t *testing.T) {
+	nodes, err := decodeSenMLJSON(t, NewObjectInstancePath(3, 0), deviceSenMLJSON)
+	assert.Nil(t, err)
+	assert.Equal(t, 13, len(nodes))
+	ress, err := GetAllResources(nodes, NewObjectInstancePath(3, 0))
+	assert.Nil(t, err)
+	res, ok := ress[NewResourcePath(3, 0, 0)]
+	assert.True(t, ok)
+	ins, err := res.GetInstance(0)
+	assert.Nil(t, err)
+	assert.Equal(t, "Open Mobile Alliance", ins.Value())
+	res, ok = ress[NewResourcePath(3, 0, 7)]
+	assert.True(t, ok)
+	assert.True(t, res.isMultiple)
+	assert.Equal(t, 2, res.InstanceCount())
+	ins, err = res.GetInstance(1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(5000), ins.Value())
+	res, ok = ress[NewResourcePath(3, 0, 13)]
+	assert.True(t, ok)
+	ins, err = res.GetInstance(0)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Unix(1367491215, 0), ins.Value())
+
+	nodes, err = decodeSenMLJSON(t, NewObjectPath(3), deviceSenMLJSON)
+	assert.Nil(t, err)
+	obj, err := GetObjectByPath(nodes, NewObjectPath(3))
+	assert.Nil(t, err)
+	assert.Equal(t, 13, len(obj.Instances[0].Resources))
+
+	nodes, err = decodeSenMLJSON(t, NewResourcePath(3, 0, 9), `[{"n":"/3/0/9","v":95}]`)
+	assert.Nil(t, err)
+	res, err = GetResourceByPath(nodes, NewResourcePath(3, 0, 9))
+	assert.Nil(t, err)
+	ins, err = res.GetInstance(0)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(95), ins.Value())
+
+	_, err = decodeSenMLJSON(t, NewObjectPath(3), `[{"n":"/4/0/9","v":95}]`)
+	assert.Equal(t, ErrPathNotMatch, err)
+
+	// values that do not match their resource are errors
+	_, err = decodeSenMLJSON(t, NewObjectInstancePath(3, 0), `[{"bn":"/3/0/","n":"9","vs":"full"},{"n":"0","vs":"OMA"}]`)
+	assert.ErrorIs(t, err, ErrResourceType)
+	assert.Contains(t, err.Error(), "/3/0/9")
+
+	// resources missing from the registry keep the decoded value
+	nodes, err = decodeSenMLJSON(t, NewObjectInstancePath(3, 0), `[{"bn":"/3/0/","n":"9999","v":1}]`)
+	assert.Nil(t, err)
+	res, err = GetResourceByPath(nodes, NewResourcePath(3, 0, 9999))
+	assert.Nil(t, err)
+	ins, err = res.GetInstance(0)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), ins.Value())
+	nodes, err = decodeSenMLJSON(t, NewRootPath(), `[{"n":"/32769/0/1","vs":"vendor"}]`)
+	assert.Nil(t, err)
+	res, err = GetResourceByPath(nodes, NewResourcePath(32769, 0, 1))
+	assert.Nil(t, err)
+	assert.Equal(t, "vendor", res.Data().StringVal())
+}
+
+func TestGetNodesByPath(t *testing.T) {
+	nodes, err := decodeSenMLJSON(t, NewObjectPath(3), deviceSenMLJSON)
+	assert.Nil(t, err)
+
+	sub, err := GetNodesByPath(nodes, NewObjectPath(3))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(sub))
+	_, ok := sub[0].(*ObjectInstance)
+	assert.True(t, ok)
+
+	sub, err = GetNodesByPath(nodes, NewObjectInstancePath(3, 0))
+	assert.Nil(t, err)
+	assert.Equal(t, 13, len(sub))
+
+	sub, err = GetNodesByPath(nodes, NewResourcePath(3, 0, 9))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(sub))
+	res, err := GetResourceByPath(sub, NewResourcePath(3, 0, 9))
+	assert.Nil(t, err)
+	ins, err := res.GetInstance(0)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(100), ins.Value())
+
+	sub, err = GetNodesByPath(nodes, NewResourceInstancePath(3, 0, 7, 1))
+	assert.Nil(t, err)
+	res = sub[0].(*Resource)
+	assert.Equal(t, 1, res.InstanceCount())
+	ins, err = res.GetInstance(1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(5000), ins.Value())
+
+	_, err = GetNodesByPath(nodes, NewResourcePath(3, 0, 20))
+	assert.Equal(t, ErrNotFound, err)
+	_, err = GetNodesByPath(nodes, NewResourceInstancePath(3, 0, 7, 5))
+	assert.Equal(t, ErrNotFound, err)
+}
+
+func TestDecodeSenMLHistory(t *testing.T) {
+	nodes, err := decodeSenMLJSON(t, NewObjectInstancePath(3303, 0), `[
+{"bn":"/3303/0/","bt":1700000000,"n":"5700","v":21.5,"t":-10},
+{"n":"5700","v":22.5},
+{"n":"5700","v":20.5,"t":-20},
+{"n":"5701","vs":"Cel"}]`)
+	assert.Nil(t, err)
+	res, err := GetResourceByPath(nodes, NewResourcePath(3303, 0, 5700))
+	assert.Nil(t, err)
+	ins, err := res.GetInstance(0)
+	assert.Nil(t, err)
+	assert.Equal(t, 22.5, ins.Value())
+	ts, ok := ins.Timestamp()
+	assert.True(t, ok)
+	assert.Equal(t, time.Unix(1700000000, 0), ts)
+
+	history := res.History(0)
+	assert.Equal(t, 3, len(history))
+	values := make([]any, 0)
+	for _, h := range history {
+		values = append(values, h.Value())
+	}
+	assert.Equal(t, []any{20.5, 21.5, 22.5}, values)
+	ts, _ = history[0].Timestamp()
+	assert.Equal(t, time.Unix(1699999980, 0), ts)
+
+	all, err := GetHistory(nodes, NewObjectInstancePath(3303, 0))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(all))
+	assert.Equal(t, 3, len(all[NewResourcePath(3303, 0, 5700)]))
+	assert.Equal(t, 1, len(all[NewResourcePath(3303, 0, 5701)]))
+	// the base time apply to records without time
+	ts, ok = all[NewResourcePath(3303, 0, 5701)][0].Timestamp()
+	assert.True(t, ok)
+	assert.Equal(t, time.Unix(1700000000, 0), ts)
+
+	// the history is kept by the nodes of a subscription path
+	for _, p := range []Path{NewObjectPath(3303), NewObjectInstancePath(3303, 0), NewResourcePath(3303, 0, 5700)} {
+		sub, err := GetNodesByPath(nodes, p)
+		assert.Nil(t, err)
+		all, err = GetHistory(sub, p)
+		assert.Nil(t, err)
+		assert.Equal(t, 3, len(all[NewResourcePath(3303, 0, 5700)]))
+	}
+
+	// values without time keep the last one
+	nodes, err = decodeSenMLJSON(t, NewResourcePath(3, 0, 7), `[
+{"bn":"/3/0/7/","n":"0","v":3800},{"n":"0","v":3900},{"n":"1","v":5000}]`)
+	assert.Nil(t, err)
+	all, err = GetHistory(nodes, NewResourceInstancePath(3, 0, 7, 0))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(all))
+	history = all[NewResourceInstancePath(3, 0, 7, 0)]
+	assert.Equal(t, 2, len(history))
+	assert.Equal(t, int64(3900), history[1].Value())
+
+	// timestamps are encoded
+	records, err := encodeSenMLMessage([]Node{res})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(records))
+	assert.Equal(t, float64(1700000000), records[0].Time)
+}
+
+func TestEncodeSenMLJSON(t *testing.T) {
+	nodes, err := decodeSingleObjectTLV(t)
+	assert.Nil(t, err)
+	r, err := EncodeMessage(message.AppSenmlJSON, nodes)
+	assert.Nil(t, err)
+	msg := pool.NewMessage(context.Background())
+	msg.SetContentFormat(message.AppSenmlJSON)
+	msg.SetBody(r)
+	decoded, err := DecodeMessage(NewObjectInstancePath(3, 0), msg)
+	assert.Nil(t, err)
+	assert.Equal(t, 13, len(decoded))
+	ress, err := GetAllResources(decoded, NewObjectInstancePath(3, 0))
+	assert.Nil(t, err)
+	res := ress[NewResourcePath(3, 0, 6)]
+	assert.Equal(t, 2, res.InstanceCount())
+	ins, err := res.GetInstance(1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(5), ins.Value())
+	ins, err = ress[NewResourcePath(3, 0, 1)].GetInstance(0)
+	assert.Nil(t, err)
+	assert.Equal(t, "Lightweight M2M Client", ins.Value())
+}
+
+func TestSenMLCborRoundTrip(t *testing.T) {
+	nodes, err := decodeSingleObjectTLV(t)
+	assert.Nil(t, err)
+	r, err := EncodeMessage(message.AppSenmlCbor, nodes)
+	assert.Nil(t, err)
+	msg := pool.NewMessage(context.Background())
+	msg.SetContentFormat(message.AppSenmlCbor)
+	msg.SetBody(r)
+	decoded, err := DecodeMessage(NewObjectInstancePath(3, 0), msg)
+	assert.Nil(t, err)
+	assert.Equal(t, 13, len(decoded))
+	origin, err := GetAllResources(nodes, NewObjectInstancePath(3, 0))
+	assert.Nil(t, err)
+	ress, err := GetAllResources(decoded, NewObjectInstancePath(3, 0))
+	assert.Nil(t, err)
+	for p, res := range origin {
+		dr, ok := ress[p]
+		assert.True(t, ok, p.String())
+		assert.Equal(t, res.InstanceCount(), dr.InstanceCount(), p.String())
+		for id, ins := range res.instances {
+			di, err := dr.GetInstance(id)
+			assert.Nil(t, err)
+			assert.Equal(t, ins.Value(), di.Value(), p.String())
+		}
+	}
+}
+
+func TestLwm2mCbor(t *testing.T) {
+	// {[3, 0]: {0: "Open", 6: {0: 1, 1: 5}, 9: 100}}
+	data, err := _strToByte(`A1 82 03 00 A3 00 64 4F 70 65 6E 06 A2 00 01 01 05 09 18 64`)
+	assert.Nil(t, err)
+	msg := pool.NewMessage(context.Background())
+	msg.SetContentFormat(message.AppLwm2mCbor)
+	msg.SetBody(bytes.NewReader(data))
+	nodes, err := DecodeMessage(NewObjectInstancePath(3, 0), msg)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(nodes))
+	res, err := GetResourceByPath(nodes, NewResourcePath(3, 0, 6))
+	assert.Nil(t, err)
+	assert.True(t, res.isMultiple)
+	ins, err := res.GetInstance(1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(5), ins.Value())
+
+	nodes, err = decodeSingleObjectTLV(t)
+	assert.Nil(t, err)
+	r, err := EncodeMessage(message.AppLwm2mCbor, nodes)
+	assert.Nil(t, err)
+	msg = pool.NewMessage(context.Background())
+	msg.SetContentFormat(message.AppLwm2mCbor)
+	msg.SetBody(r)
+	decoded, err := DecodeMessage(NewObjectPath(3), msg)
+	assert.Nil(t, err)
+	obj, err := GetObjectByPath(decoded, NewObjectPath(3))
+	assert.Nil(t, err)
+	assert.Equal(t, 13, len(obj.Instances[0].Resources))
+	ins, err = obj.Instances[0].Resources[0].GetInstance(0)
+	assert.Nil(t, err)
+	assert.Equal(t, "Open Mobile Alliance", ins.Value())
+}
+
+func TestLwm2mJSON(t *testing.T) {
+	data := `{"bn":"/3/0/","e":[{"n":"0","sv":"Open Mobile Alliance"},
+{"n":"6/0","v":1},{"n":"6/1","v":5},{"n":"9","v":95}]}`
+	msg := pool.NewMessage(context.Background())
+	msg.SetContentFormat(message.AppLwm2mJSON)
+	msg.SetBody(bytes.NewReader([]byte(data)))
+	nodes, err := DecodeMessage(NewObjectInstancePath(3, 0), msg)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(nodes))
+	res, err := GetResourceByPath(nodes, NewResourcePath(3, 0, 6))
+	assert.Nil(t, err)
+	assert.Equal(t, 2, res.InstanceCount())
+	res, err = GetResourceByPath(nodes, NewResourcePath(3, 0, 9))
+	assert.Nil(t, err)
+	ins, err := res.GetInstance(0)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(95), ins.Value())
+
+	r, err := EncodeMessage(message.AppLwm2mJSON, nodes)
+	assert.Nil(t, err)
+	msg = pool.NewMessage(context.Background())
+	msg.SetContentFormat(message.AppLwm2mJSON)
+	msg.SetBody(r)
+	body, err := msg.ReadBody()
+	assert.Nil(t, err)
+	assert.Equal(t, `{"bn":"/3/0/","e":[{"n":"0","sv":"Open Mobile Alliance"},`+
+		`{"n":"6/0","v":1},{"n":"6/1","v":5},{"n":"9","v":95}]}`, string(body))
+
+	// opaque values are base64 "sv" in LwM2M JSON only
+	msg = pool.NewMessage(context.Background())
+	msg.SetContentFormat(message.AppLwm2mJSON)
+	msg.SetBody(bytes.NewReader([]byte(`{"e":[{"n":"/5/0/0","sv":"AQIDBA=="}]}`)))
+	nodes, err = DecodeMessage(NewResourcePath(5, 0, 0), msg)
+	assert.Nil(t, err)
+	res, err = GetResourceByPath(nodes, NewResourcePath(5, 0, 0))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte{0x01, 0x02, 0x03, 0x04}, res.Data().Opaque())
+	nodes, err = decodeSenMLJSON(t, NewResourcePath(5, 0, 0), `[{"n":"/5/0/0","vs":"AQIDBA=="}]`)
+	assert.Nil(t, err)
+	res, err = GetResourceByPath(nodes, NewResourcePath(5, 0, 0))
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("AQIDBA=="), res.Data().Opaque())
+}
+
+func TestCbor(t *testing.T) {
+	{ // resource, /3/0/9 battery level 95
+		msg := pool.NewMessage(context.Background())
+		msg.SetContentFormat(message.AppCBOR)
+		msg.SetBody(bytes.NewReader([]byte{0x18, 0x5f}))
+		nodes, err := DecodeMessage(NewResourcePath(3, 0, 9), msg)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(nodes))
+		res, ok := nodes[0].(*Resource)
+		assert.True(t, ok)
+		ii, err := res.Data().Integer()
+		assert.Nil(t, err)
+		assert.Equal(t, int64(95), ii)
+		ins, err := res.GetInstance(0)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(95), ins.Value())
+
+		r, err := EncodeMessage(message.AppCBOR, nodes)
+		assert.Nil(t, err)
+		body, err := io.ReadAll(r)
+		assert.Nil(t, err)
+		assert.Equal(t, []byte{0x18, 0x5f}, body)
+	}
+	{ // resource instance, /3/0/7/1 supply voltage
+		msg := pool.NewMessage(context.Background())
+		msg.SetContentFormat(message.AppCBOR)
+		msg.SetBody(bytes.NewReader([]byte{0x19, 0x13, 0x88}))
+		nodes, err := DecodeMessage(NewResourceInstancePath(3, 0, 7, 1), msg)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(nodes))
+		res, ok := nodes[0].(*Resource)
+		assert.True(t, ok)
+		ins, err := res.GetInstance(1)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(5000), ins.Value())
+
+		r, err := EncodeMessage(message.AppCBOR, []Node{ins})
+		assert.Nil(t, err)
+		body, err := io.ReadAll(r)
+		assert.Nil(t, err)
+		assert.Equal(t, []byte{0x19, 0x13, 0x88}, body)
+	}
+	{ // path conflict
+		msg := pool.NewMessage(context.Background())
+		msg.SetContentFormat(message.AppCBOR)
+		msg.SetBody(bytes.NewReader([]byte{0x18, 0x5f}))
+		_, err := DecodeMessage(NewObjectInstancePath(3, 0), msg)
+		assert.Equal(t, ErrMediaTypePathConflict, err)
+	}
+}
+
+func TestTypedValue(t *testing.T) {
+	{ // plain text value written as TLV
+		pt, err := encoding.NewPlainTextValue(42)
+		assert.Nil(t, err)
+		res, err := NewSingleResource(NewResourcePath(1, 0, 1), pt)
+		assert.Nil(t, err)
+		r, err := EncodeMessage(message.AppLwm2mTLV, []Node{res})
+		assert.Nil(t, err)
+		body, err := io.ReadAll(r)
+		assert.Nil(t, err)
+		assert.Equal(t, []byte{0xe8, 0x00, 0x01, 0x08, 0, 0, 0, 0, 0, 0, 0, 0x2a}, body)
+	}
+	{ // Go values checked against resource type
+		res, err := NewSingleResourceValue(NewResourcePath(1, 0, 1), 300)
+		assert.Nil(t, err)
+		ins, err := res.GetInstance(0)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(300), ins.Value())
+		_, err = NewSingleResourceValue(NewResourcePath(1, 0, 1), "300")
+		assert.Equal(t, ErrResourceType, err)
+
+		res, err = NewSingleResourceValue(NewResourcePath(3, 0, 13), time.Unix(1367491215, 0))
+		assert.Nil(t, err)
+		r, err := EncodeMessage(message.TextPlain, []Node{res})
+		assert.Nil(t, err)
+		body, err := io.ReadAll(r)
+		assert.Nil(t, err)
+		assert.Equal(t, "1367491215", string(body))
+		r, err = EncodeMessage(message.AppSenmlJSON, []Node{res})
+		assert.Nil(t, err)
+		body, err = io.ReadAll(r)
+		assert.Nil(t, err)
+		assert.Equal(t, `[{"n":"/3/0/13","v":1367491215}]`, string(body))
+	}
+	{ // objlnk
+		l := encoding.ObjLink{ObjectId: 21, InstanceId: 0}
+		res, err := NewSingleResourceValue(NewResourcePath(0, 0, 17), l)
+		assert.Nil(t, err)
+		r, err := EncodeMessage(message.AppLwm2mTLV, []Node{res})
+		assert.Nil(t, err)
+		body, err := io.ReadAll(r)
+		assert.Nil(t, err)
+		assert.Equal(t, []byte{0xe4, 0x00, 0x11, 0x00, 0x15, 0x00, 0x00}, body)
+		_, err = NewSingleResourceValue(NewResourcePath(1, 0, 1), l)
+		assert.Equal(t, ErrResourceType, err)
+	}
+	{ // resources of vendor objects take the type of the value
+		res, err := NewSingleResourceValue(NewResourcePath(32769, 0, 1), 42)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(42), res.Data().(*encoding.TypedValue).Value)
+		r, err := EncodeMessage(message.AppSenmlJSON, []Node{res})
+		assert.Nil(t, err)
+		body, err := io.ReadAll(r)
+		assert.Nil(t, err)
+		assert.Equal(t, `[{"n":"/32769/0/1","v":42}]`, string(body))
+		ri, err := NewResourceInstanceValue(NewResourceInstancePath(32769, 0, 2, 1), "on")
+		assert.Nil(t, err)
+		assert.Equal(t, "on", ri.Data().StringVal())
+		_, err = NewSingleResourceValue(NewResourcePath(32769, 0, 1), struct{}{})
+		assert.Equal(t, ErrResourceType, err)
+	}
+	{ // value of unknown type resources
+		ri := &ResourceInstance{resType: R_NONE, data: &encoding.TypedValue{Value: int64(7)}}
+		assert.Equal(t, int64(7), ri.Value())
+		ri = &ResourceInstance{resType: R_NONE, data: encoding.NewPlainTextRaw([]byte("7"))}
+		assert.Equal(t, []byte("7"), ri.Value())
+	}
+}
+
+func TestUnsigned(t *testing.T) {
+	// /1/0/13 Registration Priority Order, 0xffffffffffffffff
+	data := []byte{0xe8, 0x00, 0x0d, 0x08, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	msg := pool.NewMessage(context.Background())
+	msg.SetContentFormat(message.AppLwm2mTLV)
+	msg.SetBody(bytes.NewReader(data))
+	nodes, err := DecodeMessage(NewObjectInstancePath(1, 0), msg)
+	assert.Nil(t, err)
+	res, err := GetResourceByPath(nodes, NewResourcePath(1, 0, 13))
+	assert.Nil(t, err)
+	ins, err := res.GetInstance(0)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(math.MaxUint64), ins.Value())
+
+	for _, mt := range []message.MediaType{message.TextPlain, message.AppSenmlJSON, message.AppSenmlCbor,
+		message.AppLwm2mCbor, message.AppCBOR, message.AppLwm2mTLV} {
+		r, err := EncodeMessage(mt, []Node{res})
+		assert.Nil(t, err, mt.String())
+		msg = pool.NewMessage(context.Background())
+		msg.SetContentFormat(mt)
+		msg.SetBody(r)
+		decoded, err := DecodeMessage(NewResourcePath(1, 0, 13), msg)
+		assert.Nil(t, err, mt.String())
+		dr, err := GetResourceByPath(decoded, NewResourcePath(1, 0, 13))
+		assert.Nil(t, err, mt.String())
+		di, err := dr.GetInstance(0)
+		assert.Nil(t, err, mt.String())
+		assert.Equal(t, uint64(math.MaxUint64), di.Value(), mt.String())
+	}
+
+	_, err = NewSingleResourceValue(NewResourcePath(1, 0, 13), -1)
+	assert.Equal(t, ErrResourceType, err)
+	res, err = NewSingleResourceValue(NewResourcePath(22, 0, 0), "</3/0>")
+	assert.Nil(t, err)
+	ins, err = res.GetInstance(0)
+	assert.Nil(t, err)
+	assert.Equal(t, "</3/0>", ins.Value())
+}
+
+func TestEncodeTLVOrder(t *testing.T) {
+	obj := NewObject(1)
+	for iid, values := range [][]int{{1, 300}, {2, 60}} {
+		oi := NewObjectInstance(uint16(iid))
+		for rid, v := range values {
+			res, err := NewSingleResourceValue(NewResourcePath(1, uint16(iid), uint16(rid)), v)
+			assert.Nil(t, err)
+			oi.Resources[uint16(rid)] = res
+		}
+		obj.Instances[uint16(iid)] = oi
+	}
+	// instances and resources are encoded in id order
+	for i := 0; i < 10; i++ {
+		r, err := EncodeMessage(message.AppLwm2mTLV, []Node{obj})
+		assert.Nil(t, err)
+		body, err := io.ReadAll(r)
+		assert.Nil(t, err)
+		assert.Equal(t, []byte{
+			0x28, 0x00, 0x00, 0x18,
+			0xe8, 0x00, 0x00, 0x08, 0, 0, 0, 0, 0, 0, 0, 0x01,
+			0xe8, 0x00, 0x01, 0x08, 0, 0, 0, 0, 0, 0, 0x01, 0x2c,
+			0x28, 0x00, 0x01, 0x18,
+			0xe8, 0x00, 0x00, 0x08, 0, 0, 0, 0, 0, 0, 0, 0x02,
+			0xe8, 0x00, 0x01, 0x08, 0, 0, 0, 0, 0, 0, 0, 0x3c,
+		}, body)
+	}
+}
@@ -3,6 +3,7 @@ package node
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -67,6 +68,15 @@ func NewPathFromString(s string) (Path, error) {
 	return p, nil
 }
 
+func NewRootPath() Path {
+	return Path{
+		objectId:           -1,
+		objectInstanceId:   -1,
+		resourceId:         -1,
+		resourceInstanceId: -1,
+	}
+}
+
 func NewObjectPath(objID uint16) Path {
 	return Path{
 		objectId:           int32(objID),
@@ -103,6 +113,35 @@ func NewResourceInstancePath(objID, obiID, resourceID, resiID uint16) Path {
 	}
 }
 
+// newPathFromIds create a Path from its ids, from object id to resource
+// instance id
+func newPathFromIds(ids []uint16) (Path, error) {
+	switch len(ids) {
+	case 1:
+		return NewObjectPath(ids[0]), nil
+	case 2:
+		return NewObjectInstancePath(ids[0], ids[1]), nil
+	case 3:
+		return NewResourcePath(ids[0], ids[1], ids[2]), nil
+	case 4:
+		return NewResourceInstancePath(ids[0], ids[1], ids[2], ids[3]), nil
+	}
+	return Path{}, ErrPathInvalidValue
+}
+
+// ids return the not null ids of the path, from object id to resource
+// instance id
+func (p *Path) ids() []uint16 {
+	ids := make([]uint16, 0, 4)
+	for _, id := range []int32{p.objectId, p.objectInstanceId, p.resourceId, p.resourceInstanceId} {
+		if id < 0 {
+			break
+		}
+		ids = append(ids, uint16(id))
+	}
+	return ids
+}
+
 func (p *Path) ObjectId() (uint16, error) {
 	if p.objectId < 0 {
 		return 0, ErrPathNilValue
@@ -226,3 +265,28 @@ func (p *Path) String() string {
 	}
 	return b.String()
 }
+
+// Parent return the parent path of p, the root path of an object path
+func (p *Path) Parent() (Path, error) {
+	ids := p.ids()
+	switch len(ids) {
+	case 0:
+		return Path{}, ErrPathInvalidValue
+	case 1:
+		return NewRootPath(), nil
+	}
+	return newPathFromIds(ids[:len(ids)-1])
+}
+
+// SortPaths sort paths by their ids, a parent path come before its children
+func SortPaths(paths []Path) {
+	sort.Slice(paths, func(i, j int) bool {
+		a, b := paths[i].ids(), paths[j].ids()
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+}
@@ -34,3 +34,21 @@ func TestNewPathFromString(t *testing.T) {
 	assert.Equal(t, true, p.IsResourceInstance())
 	assert.Equal(t, "/3/4/5/6", p.String())
 }
+
+func TestPathParent(t *testing.T) {
+	p := NewResourceInstancePath(3, 0, 7, 1)
+	pp, err := p.Parent()
+	assert.Nil(t, err)
+	assert.Equal(t, NewResourcePath(3, 0, 7), pp)
+	pp, err = pp.Parent()
+	assert.Nil(t, err)
+	assert.Equal(t, NewObjectInstancePath(3, 0), pp)
+	pp, err = pp.Parent()
+	assert.Nil(t, err)
+	assert.Equal(t, NewObjectPath(3), pp)
+	pp, err = pp.Parent()
+	assert.Nil(t, err)
+	assert.True(t, pp.IsRoot())
+	_, err = pp.Parent()
+	assert.Equal(t, ErrPathInvalidValue, err)
+}
@@ -6,6 +6,7 @@ import (
 	"embed"
 	"encoding/xml"
 	"errors"
+	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
@@ -47,20 +48,26 @@ func (r ResourceType) String() string {
 		return "R_TIME"
 	case R_OBJLNK:
 		return "R_OBJLNK"
+	case R_UNSIGNED:
+		return "R_UNSIGNED"
+	case R_CORELNK:
+		return "R_CORELNK"
 	default:
 		return ""
 	}
 }
 
 var (
-	R_NONE    ResourceType = 0
-	R_STRING  ResourceType = 1
-	R_INTEGER ResourceType = 2
-	R_FLOAT   ResourceType = 3
-	R_BOOLEAN ResourceType = 4
-	R_OPAQUE  ResourceType = 5
-	R_TIME    ResourceType = 6
-	R_OBJLNK  ResourceType = 7
+	R_NONE     ResourceType = 0
+	R_STRING   ResourceType = 1
+	R_INTEGER  ResourceType = 2
+	R_FLOAT    ResourceType = 3
+	R_BOOLEAN  ResourceType = 4
+	R_OPAQUE   ResourceType = 5
+	R_TIME     ResourceType = 6
+	R_OBJLNK   ResourceType = 7
+	R_UNSIGNED ResourceType = 8
+	R_CORELNK  ResourceType = 9
 )
 
 type ResourceDefinition struct {
@@ -183,6 +190,36 @@ func (r *Registry) DetectResourceType(p Path) (ResourceType, error) {
 	return R_NONE, ErrNotFound
 }
 
+// GetResourceDefinition return the definition of the resource of p, p is a
+// resource or resource instance path
+func (r *Registry) GetResourceDefinition(p Path) (*ResourceDefinition, error) {
+	if !(p.IsResource() || p.IsResourceInstance()) {
+		return nil, ErrPathInvalidValue
+	}
+	if o, ok := r.objs[uint16(p.objectId)]; ok {
+		if r, ok := o.Resources[uint16(p.resourceId)]; ok {
+			return r, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// CheckOperation return ErrOperationNotAllowed if the resource of p is
+// defined without op, resources unknown to the registry are not checked
+func (r *Registry) CheckOperation(p Path, op ResourceOperations) error {
+	def, err := r.GetResourceDefinition(p)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if def.Operations&op != op {
+		return fmt.Errorf("resource %v %v: %w", p.String(), def.Name, ErrOperationNotAllowed)
+	}
+	return nil
+}
+
 func loadObjectDefinition(x []byte) (*ObjectDefinition, error) {
 	var xx xLWM2M
 	if err := xml.Unmarshal(x, &xx); err != nil {
@@ -235,6 +272,10 @@ func strToResourceType(str string) ResourceType {
 		return R_TIME
 	case "Objlnk":
 		return R_OBJLNK
+	case "Unsigned Integer":
+		return R_UNSIGNED
+	case "Corelnk":
+		return R_CORELNK
 	default:
 		return R_NONE
 
@@ -178,3 +178,27 @@ func TestSingleObject(t *testing.T) {
 	assert.Equal(t, obj.Resources[5700].Type, R_FLOAT)
 	assert.Equal(t, obj.Resources[5700].Description, "Last or Current Measured Value from the Sensor.")
 }
+
+func TestResourceTypes(t *testing.T) {
+	reg := GetRegistry()
+	rt, err := reg.DetectResourceType(NewResourcePath(1, 0, 13))
+	assert.Nil(t, err)
+	assert.Equal(t, R_UNSIGNED, rt)
+	rt, err = reg.DetectResourceType(NewResourcePath(22, 0, 0))
+	assert.Nil(t, err)
+	assert.Equal(t, R_CORELNK, rt)
+}
+
+func TestCheckOperation(t *testing.T) {
+	reg := GetRegistry()
+	assert.Nil(t, reg.CheckOperation(NewResourcePath(3, 0, 4), OP_E))
+	assert.ErrorIs(t, reg.CheckOperation(NewResourcePath(3, 0, 0), OP_E), ErrOperationNotAllowed)
+	assert.Nil(t, reg.CheckOperation(NewResourcePath(3, 0, 0), OP_R))
+	// not defined resources are not checked
+	assert.Nil(t, reg.CheckOperation(NewResourcePath(3, 0, 60000), OP_E))
+	def, err := reg.GetResourceDefinition(NewResourcePath(3, 0, 4))
+	assert.Nil(t, err)
+	assert.Equal(t, "Reboot", def.Name)
+	_, err = reg.GetResourceDefinition(NewObjectPath(3))
+	assert.Equal(t, ErrPathInvalidValue, err)
+}
@@ -1,9 +1,13 @@
 package node
 
 import (
+	"errors"
 	"fmt"
 	"github.com/yplam/lwm2m/encoding"
+	"math"
+	"sort"
 	"strings"
+	"time"
 )
 
 type ResourceInstance struct {
@@ -11,18 +15,35 @@ type ResourceInstance struct {
 	resType ResourceType
 	data    encoding.Valuer
 	path    Path
+	// timestamp is the time of the value if the client sent one
+	timestamp time.Time
 }
 
+// Value return the value of the instance as the Go type of its resource
+// type: string, int64, uint64, float64, bool, []byte, time.Time or
+// encoding.ObjLink, Corelnk is returned as string, nil if the data can not
+// be converted. For resources of unknown type it is the value decoded from a
+// SenML or LwM2M JSON/CBOR record, or the raw bytes of other formats
 func (r *ResourceInstance) Value() interface{} {
 	switch r.resType {
 	case R_NONE:
-		return r.data
-	case R_STRING:
+		switch v := r.data.(type) {
+		case nil:
+			return nil
+		case *encoding.TypedValue:
+			return v.Value
+		}
+		return r.data.Raw()
+	case R_STRING, R_CORELNK:
 		return r.data.StringVal()
 	case R_INTEGER:
 		if d, err := r.data.Integer(); err == nil {
 			return d
 		}
+	case R_UNSIGNED:
+		if d, err := r.data.Unsigned(); err == nil {
+			return d
+		}
 	case R_FLOAT:
 		if d, err := r.data.Float(); err == nil {
 			return d
@@ -35,11 +56,11 @@ func (r *ResourceInstance) Value() interface{} {
 		return r.data.Opaque()
 	case R_TIME:
 		if d, err := r.data.Time(); err == nil {
-			return d
+			return time.Unix(d, 0)
 		}
 	case R_OBJLNK:
 		if d0, d1, err := r.data.ObjectLink(); err == nil {
-			return [2]uint16{d0, d1}
+			return encoding.ObjLink{ObjectId: d0, InstanceId: d1}
 		}
 	}
 	return nil
@@ -57,30 +78,172 @@ func (r *ResourceInstance) Data() encoding.Valuer {
 	return r.data
 }
 
+// Timestamp return the time the value was measured, it is set for values
+// decoded from SenML records with a time, e.g. stored notifications
+func (r *ResourceInstance) Timestamp() (time.Time, bool) {
+	return r.timestamp, !r.timestamp.IsZero()
+}
+
+// SetTimestamp set the time the value was measured, the zero time mean the
+// value has no timestamp
+func (r *ResourceInstance) SetTimestamp(t time.Time) {
+	r.timestamp = t
+}
+
+// isBefore order values by timestamp, a value without timestamp is the
+// current value and is after any timestamped one
+func (r *ResourceInstance) isBefore(o *ResourceInstance) bool {
+	if r.timestamp.IsZero() {
+		return false
+	}
+	if o.timestamp.IsZero() {
+		return true
+	}
+	return r.timestamp.Before(o.timestamp)
+}
+
 func NewResourceInstance(p Path, data encoding.Valuer) (r *ResourceInstance, err error) {
+	resType, err := GetRegistry().DetectResourceType(p)
+	if err != nil {
+		return
+	}
+	return newResourceInstance(p, resType, data), nil
+}
+
+// newUnknownResourceInstance create an instance of a resource missing from
+// the registry, e.g. of a vendor object, its value is kept as decoded
+func newUnknownResourceInstance(p Path, data encoding.Valuer) (*ResourceInstance, error) {
+	if !(p.IsResource() || p.IsResourceInstance()) {
+		return nil, ErrPathInvalidValue
+	}
+	return newResourceInstance(p, R_NONE, data), nil
+}
+
+func newResourceInstance(p Path, resType ResourceType, data encoding.Valuer) *ResourceInstance {
 	id, err := p.ResourceInstanceId()
 	if err != nil {
 		id = 0
 		p.resourceInstanceId = 0
 	}
-	resType, err := GetRegistry().DetectResourceType(p)
-	if err != nil {
-		return
-	}
-	r = &ResourceInstance{
+	return &ResourceInstance{
 		id:      id,
 		resType: resType,
 		data:    data,
 		path:    p,
 	}
+}
+
+// NewResourceInstanceValue create a resource instance from a Go value, v
+// must match the resource type in registry, it is kept as is and encoded
+// to the media type selected when sending. The type of a resource missing
+// from the registry is the one of v
+func NewResourceInstanceValue(p Path, v any) (r *ResourceInstance, err error) {
+	r, err = NewResourceInstance(p, nil)
+	if errors.Is(err, ErrNotFound) {
+		r, err = newUnknownResourceInstance(p, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	tv, err := typedValue(r.resType, v)
+	if err != nil {
+		return nil, err
+	}
+	r.data = &encoding.TypedValue{Value: tv}
 	return
 }
 
+// typedValue check v against resource type t, and convert it to the Go
+// type used by Value, integers of other sizes are accepted
+func typedValue(t ResourceType, v any) (any, error) {
+	switch vv := v.(type) {
+	case int:
+		v = int64(vv)
+	case int8:
+		v = int64(vv)
+	case int16:
+		v = int64(vv)
+	case int32:
+		v = int64(vv)
+	case uint:
+		v = uint64(vv)
+	case uint8:
+		v = uint64(vv)
+	case uint16:
+		v = uint64(vv)
+	case uint32:
+		v = uint64(vv)
+	case float32:
+		v = float64(vv)
+	}
+	switch t {
+	case R_STRING, R_CORELNK:
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+	case R_INTEGER:
+		switch vv := v.(type) {
+		case int64:
+			return vv, nil
+		case uint64:
+			if vv <= math.MaxInt64 {
+				return int64(vv), nil
+			}
+		}
+	case R_UNSIGNED:
+		switch vv := v.(type) {
+		case uint64:
+			return vv, nil
+		case int64:
+			if vv >= 0 {
+				return uint64(vv), nil
+			}
+		}
+	case R_FLOAT:
+		switch vv := v.(type) {
+		case int64:
+			return float64(vv), nil
+		case uint64:
+			return float64(vv), nil
+		case float64:
+			return vv, nil
+		}
+	case R_BOOLEAN:
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+	case R_OPAQUE:
+		if b, ok := v.([]byte); ok {
+			return b, nil
+		}
+	case R_TIME:
+		switch vv := v.(type) {
+		case int64:
+			return time.Unix(vv, 0), nil
+		case time.Time:
+			return vv, nil
+		}
+	case R_OBJLNK:
+		if l, ok := v.(encoding.ObjLink); ok {
+			return l, nil
+		}
+	case R_NONE:
+		switch v.(type) {
+		case int64, uint64, float64, string, bool, []byte, time.Time, encoding.ObjLink:
+			return v, nil
+		}
+	}
+	return nil, ErrResourceType
+}
+
 type Resource struct {
 	id         uint16
 	isMultiple bool
 	instances  map[uint16]*ResourceInstance
 	path       Path
+	// history hold every value of an instance decoded from a message with
+	// several values of it, oldest first
+	history map[uint16][]*ResourceInstance
 }
 
 func (r *Resource) SetInstance(val *ResourceInstance) error {
@@ -95,6 +258,50 @@ func (r *Resource) SetInstance(val *ResourceInstance) error {
 	return ErrPathNotMatch
 }
 
+// addSample add a value of an instance decoded with other values of the
+// same instance, the instance is set to the latest value
+func (r *Resource) addSample(val *ResourceInstance) error {
+	riid, err := val.path.ResourceInstanceId()
+	if err != nil {
+		return ErrPathNotMatch
+	}
+	cur, ok := r.instances[riid]
+	if !ok {
+		return r.SetInstance(val)
+	}
+	if r.history == nil {
+		r.history = make(map[uint16][]*ResourceInstance)
+	}
+	samples := r.history[riid]
+	if len(samples) == 0 {
+		samples = []*ResourceInstance{cur}
+	}
+	samples = append(samples, val)
+	sort.SliceStable(samples, func(i, j int) bool { return samples[i].isBefore(samples[j]) })
+	r.history[riid] = samples
+	return r.SetInstance(samples[len(samples)-1])
+}
+
+// History return the values of instance index, oldest first. It has more
+// than one value when the client sent several timestamped values, e.g.
+// notifications stored while offline, the last one is the instance value
+func (r *Resource) History(index uint16) []*ResourceInstance {
+	if samples := r.history[index]; len(samples) > 0 {
+		h := make([]*ResourceInstance, len(samples))
+		copy(h, samples)
+		return h
+	}
+	if ins, ok := r.instances[index]; ok {
+		return []*ResourceInstance{ins}
+	}
+	return nil
+}
+
+// InstanceIds return the ids of the instances in ascending order
+func (r *Resource) InstanceIds() []uint16 {
+	return sortedIds(r.instances)
+}
+
 func (r *Resource) InstanceCount() int {
 	return len(r.instances)
 }
@@ -157,3 +364,19 @@ func NewSingleResource(p Path, val encoding.Valuer) (r *Resource, err error) {
 	err = r.SetInstance(ri)
 	return
 }
+
+// NewSingleResourceValue create a single resource from a Go value, see
+// NewResourceInstanceValue
+func NewSingleResourceValue(p Path, v any) (r *Resource, err error) {
+	r, err = NewResource(p, false)
+	if err != nil {
+		return
+	}
+	p.SetResourceInstanceId(0)
+	ri, err := NewResourceInstanceValue(p, v)
+	if err != nil {
+		return nil, err
+	}
+	err = r.SetInstance(ri)
+	return
+}
@@ -0,0 +1,83 @@
+package node
+
+import (
+	"bytes"
+	"github.com/plgd-dev/go-coap/v3/message"
+	"github.com/yplam/lwm2m/encoding"
+	"io"
+	"time"
+)
+
+// decodeSenMLMessage build nodes from SenML records, base64Strings is set
+// for formats that carry opaque values as base64 string
+func decodeSenMLMessage(basePath Path, records []*encoding.SenMLRecord, base64Strings bool) ([]Node, error) {
+	values := make([]resourceValue, 0, len(records))
+	now := time.Now()
+	for _, r := range encoding.ResolveSenML(records) {
+		if r.Value == nil {
+			continue
+		}
+		p, err := NewPathFromString(r.Name)
+		if err != nil {
+			return nil, err
+		}
+		v := resourceValue{
+			path: p,
+			data: &encoding.TypedValue{Value: r.Value, Base64: base64Strings},
+		}
+		if t, ok := encoding.SenMLTime(r.Time, now); ok {
+			v.t = t
+		}
+		values = append(values, v)
+	}
+	return buildNodes(basePath, values)
+}
+
+func encodeSenMLMessage(nodes []Node) ([]*encoding.SenMLRecord, error) {
+	records := make([]*encoding.SenMLRecord, 0)
+	err := walkResourceInstances(nodes, func(p Path, ri *ResourceInstance) error {
+		v, err := flatValue(ri)
+		if err != nil {
+			return err
+		}
+		r := &encoding.SenMLRecord{
+			Name:  p.String(),
+			Value: v,
+		}
+		if t, ok := ri.Timestamp(); ok {
+			r.Time = float64(t.UnixNano()) / 1e9
+		}
+		records = append(records, r)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// EncodePathList encode paths to a SenML pack of records without value, it
+// is the payload of composite operations like Read-Composite
+func EncodePathList(t message.MediaType, paths []Path) (io.ReadSeeker, error) {
+	if len(paths) == 0 {
+		return nil, ErrEmpty
+	}
+	records := make([]*encoding.SenMLRecord, 0, len(paths))
+	for _, p := range paths {
+		records = append(records, &encoding.SenMLRecord{Name: p.String()})
+	}
+	var c []byte
+	var err error
+	switch t {
+	case message.AppSenmlJSON:
+		c, err = encoding.EncodeSenMLJSON(records)
+	case message.AppSenmlCbor:
+		c, err = encoding.EncodeSenMLCbor(records)
+	default:
+		return nil, ErrContentFormatNotSupport
+	}
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(c), nil
+}
@@ -78,7 +78,12 @@ func (h *Handler) handleRegistration(w mux.ResponseWriter, r *mux.Message) {
 	var links []*encoding.CoreLink
 	if r.Body() != nil {
 		if b, err2 := io.ReadAll(r.Body()); err2 == nil {
-			links, _ = encoding.CoreLinksFromString(string(b))
+			links, err2 = encoding.CoreLinksFromString(string(b))
+			if err2 != nil {
+				h.logger.Warnf("parse core links err %v", err2)
+				h.handleBadRequest(w)
+				return
+			}
 		}
 	}
 	d, err := h.manager.Register(req, links, w.Conn())
@@ -117,7 +122,12 @@ func (h *Handler) handleUpdate(w mux.ResponseWriter, r *mux.Message, id string)
 	var links []*encoding.CoreLink
 	if r.Body() != nil {
 		if b, err2 := io.ReadAll(r.Body()); err2 == nil {
-			links, _ = encoding.CoreLinksFromString(string(b))
+			links, err2 = encoding.CoreLinksFromString(string(b))
+			if err2 != nil {
+				h.logger.Warnf("parse core links err %v", err2)
+				h.handleBadRequest(w)
+				return
+			}
 		}
 	}
 	err = h.manager.Update(id, req, links, w.Conn())
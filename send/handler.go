@@ -0,0 +1,88 @@
+package send
+
+import (
+	"github.com/pion/logging"
+	"github.com/plgd-dev/go-coap/v3/message"
+	"github.com/plgd-dev/go-coap/v3/message/codes"
+	"github.com/plgd-dev/go-coap/v3/mux"
+	"github.com/yplam/lwm2m/core"
+	"github.com/yplam/lwm2m/node"
+)
+
+// OnSendFunc call when a registered device push data with a Send
+// operation, nodes are decoded from the root path.
+// The response is sent after the callback return, please do not block in it
+type OnSendFunc func(d *core.Device, nodes []node.Node)
+
+type Handler struct {
+	logger  logging.LeveledLogger
+	manager core.Manager
+	onSend  OnSendFunc
+}
+
+func (h *Handler) ServeCOAP(w mux.ResponseWriter, r *mux.Message) {
+	if r.Code() != codes.POST {
+		h.logger.Warnf("unsupported code %v", r.Code())
+		_ = w.SetResponse(codes.MethodNotAllowed, message.TextPlain, nil)
+		return
+	}
+	// only registered devices can send, the connection is the sender identity
+	getter, ok := h.manager.(core.DeviceConnGetter)
+	if !ok {
+		h.logger.Warnf("manager can not find devices by connection")
+		_ = w.SetResponse(codes.Forbidden, message.TextPlain, nil)
+		return
+	}
+	d, err := getter.GetDeviceByConn(w.Conn())
+	if err != nil {
+		h.logger.Warnf("send from unregistered client %v", w.Conn().RemoteAddr())
+		_ = w.SetResponse(codes.Forbidden, message.TextPlain, nil)
+		return
+	}
+	d.MarkAwake()
+	ct, err := r.ContentFormat()
+	if err != nil || (ct != message.AppSenmlJSON && ct != message.AppSenmlCbor) {
+		h.logger.Warnf("unsupported content format %v", ct)
+		_ = w.SetResponse(codes.UnsupportedMediaType, message.TextPlain, nil)
+		return
+	}
+	if r.Body() == nil {
+		_ = w.SetResponse(codes.BadRequest, message.TextPlain, nil)
+		return
+	}
+	nodes, err := node.DecodeMessage(node.NewRootPath(), r.Message)
+	if err != nil {
+		h.logger.Warnf("decode send payload err %v", err)
+		_ = w.SetResponse(codes.BadRequest, message.TextPlain, nil)
+		return
+	}
+	h.logger.Debugf("send from %v", d.Endpoint)
+	d.UpdateState(node.NewRootPath(), nodes, core.StateSend)
+	if err = w.SetResponse(codes.Changed, message.TextPlain, nil); err != nil {
+		h.logger.Warnf("handling with error: %v", err)
+		return
+	}
+	if h.onSend != nil {
+		h.onSend(d, nodes)
+	}
+}
+
+// EnableHandler handle the Send operation of LwM2M 1.1 clients registered to
+// m, data is delivered to onSend. m must implement core.DeviceConnGetter,
+// Send requests are refused otherwise
+func EnableHandler(r *mux.Router, m core.Manager, onSend OnSendFunc, opts ...Option) {
+	cfg := newConfig()
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.logger == nil {
+		lf := logging.NewDefaultLoggerFactory()
+		cfg.logger = lf.NewLogger("send")
+	}
+	h := &Handler{
+		logger:  cfg.logger,
+		manager: m,
+		onSend:  onSend,
+	}
+	_ = r.Handle("/dp", h)
+}
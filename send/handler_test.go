@@ -0,0 +1,122 @@
+package send
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/plgd-dev/go-coap/v3/message"
+	"github.com/plgd-dev/go-coap/v3/message/codes"
+	"github.com/plgd-dev/go-coap/v3/mux"
+	coapNet "github.com/plgd-dev/go-coap/v3/net"
+	"github.com/plgd-dev/go-coap/v3/options"
+	"github.com/plgd-dev/go-coap/v3/udp"
+	udpClient "github.com/plgd-dev/go-coap/v3/udp/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/yplam/lwm2m/core"
+	"github.com/yplam/lwm2m/node"
+	"github.com/yplam/lwm2m/registration"
+)
+
+type sendEvent struct {
+	ep    string
+	nodes []node.Node
+}
+
+// plainManager hide the optional interfaces of the default manager
+type plainManager struct {
+	core.Manager
+}
+
+// newTestServer start a server with registration and send handlers, and
+// return a client connection to it, the send handler use wrap of the
+// manager if not nil
+func newTestServer(t *testing.T, wrap func(m core.Manager) core.Manager) (*udpClient.Conn, chan sendEvent) {
+	l, err := coapNet.NewListenUDP("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	events := make(chan sendEvent, 1)
+	r := mux.NewRouter()
+	m := core.DefaultManager(core.WithContext(ctx))
+	registration.EnableHandler(r, m)
+	sm := m
+	if wrap != nil {
+		sm = wrap(m)
+	}
+	EnableHandler(r, sm, func(d *core.Device, nodes []node.Node) {
+		events <- sendEvent{ep: d.Endpoint, nodes: nodes}
+	})
+	s := udp.NewServer(options.WithMux(r))
+	go func() {
+		_ = s.Serve(l)
+	}()
+	conn, err := udp.Dial(l.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cancel()
+		_ = conn.Close()
+		s.Stop()
+		_ = l.Close()
+	})
+	return conn, events
+}
+
+func TestSend(t *testing.T) {
+	conn, events := newTestServer(t, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	payload := []byte(`[{"bn":"/3303/0/","n":"5700","v":21.5},{"bn":"/3/0/","n":"9","v":95}]`)
+
+	// unregistered clients are rejected
+	resp, err := conn.Post(ctx, "/dp", message.AppSenmlJSON, bytes.NewReader(payload))
+	assert.Nil(t, err)
+	assert.Equal(t, codes.Forbidden, resp.Code())
+
+	resp, err = conn.Post(ctx, "/rd", message.AppLinkFormat, bytes.NewReader([]byte("</3/0>,</3303/0>")),
+		message.Option{ID: message.URIQuery, Value: []byte("ep=test")},
+		message.Option{ID: message.URIQuery, Value: []byte("lwm2m=1.1")})
+	assert.Nil(t, err)
+	assert.Equal(t, codes.Created, resp.Code())
+
+	resp, err = conn.Post(ctx, "/dp", message.TextPlain, bytes.NewReader([]byte("21.5")))
+	assert.Nil(t, err)
+	assert.Equal(t, codes.UnsupportedMediaType, resp.Code())
+
+	resp, err = conn.Post(ctx, "/dp", message.AppSenmlJSON, bytes.NewReader(payload))
+	assert.Nil(t, err)
+	assert.Equal(t, codes.Changed, resp.Code())
+	e := <-events
+	assert.Equal(t, "test", e.ep)
+	ress, err := node.GetAllResources(e.nodes, node.NewRootPath())
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(ress))
+	res, ok := ress[node.NewResourcePath(3303, 0, 5700)]
+	assert.True(t, ok)
+	ins, err := res.GetInstance(0)
+	assert.Nil(t, err)
+	assert.Equal(t, 21.5, ins.Value())
+}
+
+func TestSendWithoutConnGetter(t *testing.T) {
+	conn, events := newTestServer(t, func(m core.Manager) core.Manager {
+		return plainManager{m}
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resp, err := conn.Post(ctx, "/rd", message.AppLinkFormat, bytes.NewReader([]byte("</3/0>")),
+		message.Option{ID: message.URIQuery, Value: []byte("ep=test")},
+		message.Option{ID: message.URIQuery, Value: []byte("lwm2m=1.1")})
+	assert.Nil(t, err)
+	assert.Equal(t, codes.Created, resp.Code())
+
+	// the sender can not be identified, registered or not
+	resp, err = conn.Post(ctx, "/dp", message.AppSenmlJSON, bytes.NewReader([]byte(`[{"n":"/3/0/9","v":95}]`)))
+	assert.Nil(t, err)
+	assert.Equal(t, codes.Forbidden, resp.Code())
+	assert.Equal(t, 0, len(events))
+}